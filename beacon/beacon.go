@@ -0,0 +1,557 @@
+package beacon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/labbcb/brave/search"
+	"github.com/labbcb/brave/variant"
+)
+
+const (
+	// APIVersion is the Beacon specification version implemented.
+	APIVersion = "v2.0.0"
+	// VariantSchema is the default schema of genomic variation records.
+	VariantSchema = "ga4gh-beacon-variant-v2.0.0"
+
+	// Boolean granularity only reports whether variants exist.
+	Boolean = "boolean"
+	// Count granularity reports number of matching variants.
+	Count = "count"
+	// Record granularity returns matching variants.
+	Record = "record"
+
+	// DefaultLimit is the number of records returned when pagination is not requested.
+	DefaultLimit = 10
+)
+
+// Info identifies this Beacon.
+type Info struct {
+	ID           string       `json:"id"`
+	Name         string       `json:"name"`
+	APIVersion   string       `json:"apiVersion"`
+	Environment  string       `json:"environment"`
+	Organization Organization `json:"organization"`
+	Description  string       `json:"description,omitempty"`
+	Version      string       `json:"version,omitempty"`
+	WelcomeURL   string       `json:"welcomeUrl,omitempty"`
+}
+
+// Organization is the organization responsible for this Beacon.
+type Organization struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	WelcomeURL  string `json:"welcomeUrl,omitempty"`
+}
+
+// DefaultInfo describes a BraVE server.
+var DefaultInfo = Info{
+	ID:          "org.bipmed.brave",
+	Name:        "BraVE - BIPMed Variant Explorer",
+	APIVersion:  APIVersion,
+	Environment: "prod",
+	Organization: Organization{
+		ID:         "BIPMed",
+		Name:       "Brazilian Initiative on Precision Medicine",
+		WelcomeURL: "https://bipmed.org",
+	},
+	Description: "Population allele frequencies of Brazilian genomic datasets.",
+	WelcomeURL:  "https://bipmed.org",
+}
+
+// Request is a genomic variant query. Coordinates are 0-based as defined by Beacon.
+type Request struct {
+	ReferenceName  string
+	Start          *int64
+	End            *int64
+	ReferenceBases string
+	AlternateBases string
+	AssemblyID     string
+	GeneID         string
+	Granularity    string
+	Skip           int64
+	Limit          int64
+}
+
+// requestBody is the POST request of Beacon framework.
+type requestBody struct {
+	Meta struct {
+		RequestedGranularity string `json:"requestedGranularity"`
+	} `json:"meta"`
+	Query struct {
+		RequestParameters    map[string]interface{} `json:"requestParameters"`
+		Pagination           *pagination            `json:"pagination"`
+		RequestedGranularity string                 `json:"requestedGranularity"`
+	} `json:"query"`
+}
+
+type pagination struct {
+	Skip  int64 `json:"skip"`
+	Limit int64 `json:"limit"`
+}
+
+// ParseQuery reads request parameters from URL query (GET requests).
+func ParseQuery(values url.Values) (*Request, error) {
+	params := make(map[string]string)
+	for k := range values {
+		params[k] = values.Get(k)
+	}
+	return parse(params)
+}
+
+// ParseBody reads request parameters from Beacon request body (POST requests).
+func ParseBody(b []byte) (*Request, error) {
+	var body requestBody
+	if err := json.Unmarshal(b, &body); err != nil {
+		return nil, err
+	}
+
+	params := make(map[string]string)
+	for k, v := range body.Query.RequestParameters {
+		// start and end may be arrays, only the first value is used
+		if xs, ok := v.([]interface{}); ok {
+			if len(xs) == 0 {
+				continue
+			}
+			v = xs[0]
+		}
+		if f, ok := v.(float64); ok {
+			params[k] = strconv.FormatFloat(f, 'f', -1, 64)
+		} else {
+			params[k] = fmt.Sprint(v)
+		}
+	}
+	if body.Query.Pagination != nil {
+		params["skip"] = strconv.FormatInt(body.Query.Pagination.Skip, 10)
+		params["limit"] = strconv.FormatInt(body.Query.Pagination.Limit, 10)
+	}
+	params["requestedGranularity"] = body.Query.RequestedGranularity
+	if params["requestedGranularity"] == "" {
+		params["requestedGranularity"] = body.Meta.RequestedGranularity
+	}
+	return parse(params)
+}
+
+func parse(params map[string]string) (*Request, error) {
+	r := &Request{
+		ReferenceName:  strings.TrimPrefix(params["referenceName"], "chr"),
+		ReferenceBases: strings.ToUpper(params["referenceBases"]),
+		AlternateBases: strings.ToUpper(params["alternateBases"]),
+		AssemblyID:     params["assemblyId"],
+		GeneID:         params["geneId"],
+		Granularity:    params["requestedGranularity"],
+		Limit:          DefaultLimit,
+	}
+
+	var err error
+	if r.Start, err = parseInt(params, "start"); err != nil {
+		return nil, err
+	}
+	if r.End, err = parseInt(params, "end"); err != nil {
+		return nil, err
+	}
+	if skip, err := parseInt(params, "skip"); err != nil {
+		return nil, err
+	} else if skip != nil {
+		r.Skip = *skip
+	}
+	if limit, err := parseInt(params, "limit"); err != nil {
+		return nil, err
+	} else if limit != nil && *limit > 0 {
+		r.Limit = *limit
+	}
+
+	switch r.Granularity {
+	case "":
+		r.Granularity = Record
+	case Boolean, Count, Record:
+	default:
+		return nil, fmt.Errorf("invalid requestedGranularity %q", r.Granularity)
+	}
+
+	if r.GeneID == "" && (r.ReferenceName == "" || r.Start == nil) {
+		return nil, fmt.Errorf("referenceName and start, or geneId, are required")
+	}
+	return r, nil
+}
+
+// parseInt parses an optional integer parameter. Comma-separated values use the first value.
+func parseInt(params map[string]string, key string) (*int64, error) {
+	s, ok := params[key]
+	if !ok || s == "" {
+		return nil, nil
+	}
+	s = strings.SplitN(s, ",", 2)[0]
+	i, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return &i, nil
+}
+
+// Input converts Beacon request to search input.
+// Beacon coordinates are 0-based, start of BraVE variants is 1-based (VCF POS).
+// Without end it searches for variants at start position, otherwise variants overlapping [start, end).
+func (r *Request) Input() *search.Input {
+	q := &search.Query{
+		AssemblyID:     r.AssemblyID,
+		GeneSymbol:     r.GeneID,
+		ReferenceName:  r.ReferenceName,
+		ReferenceBases: r.ReferenceBases,
+		AlternateBases: r.AlternateBases,
+	}
+	if r.Start != nil {
+		q.Start = int32(*r.Start + 1)
+	}
+	if r.End != nil {
+		q.End = int32(*r.End)
+	}
+	return &search.Input{Start: r.Skip, Length: r.Limit, Queries: []*search.Query{q}}
+}
+
+// Meta is the information about the response.
+type Meta struct {
+	BeaconID               string           `json:"beaconId"`
+	APIVersion             string           `json:"apiVersion"`
+	ReturnedGranularity    string           `json:"returnedGranularity,omitempty"`
+	ReceivedRequestSummary *RequestSummary  `json:"receivedRequestSummary,omitempty"`
+	ReturnedSchemas        []ReturnedSchema `json:"returnedSchemas"`
+}
+
+// RequestSummary echoes the request.
+type RequestSummary struct {
+	APIVersion           string            `json:"apiVersion"`
+	RequestedSchemas     []ReturnedSchema  `json:"requestedSchemas"`
+	Pagination           pagination        `json:"pagination"`
+	RequestedGranularity string            `json:"requestedGranularity"`
+	RequestParameters    map[string]string `json:"requestParameters,omitempty"`
+}
+
+// ReturnedSchema names the schema of returned entities.
+type ReturnedSchema struct {
+	EntityType string `json:"entityType"`
+	Schema     string `json:"schema"`
+}
+
+var variantSchemas = []ReturnedSchema{{EntityType: "genomicVariation", Schema: VariantSchema}}
+
+// NewMeta builds response metadata.
+func NewMeta(info *Info, r *Request) Meta {
+	m := Meta{BeaconID: info.ID, APIVersion: APIVersion, ReturnedSchemas: variantSchemas}
+	if r != nil {
+		m.ReturnedGranularity = r.Granularity
+		m.ReceivedRequestSummary = r.summary()
+	}
+	return m
+}
+
+func (r *Request) summary() *RequestSummary {
+	params := map[string]string{}
+	if r.ReferenceName != "" {
+		params["referenceName"] = r.ReferenceName
+	}
+	if r.Start != nil {
+		params["start"] = strconv.FormatInt(*r.Start, 10)
+	}
+	if r.End != nil {
+		params["end"] = strconv.FormatInt(*r.End, 10)
+	}
+	if r.AssemblyID != "" {
+		params["assemblyId"] = r.AssemblyID
+	}
+	if r.GeneID != "" {
+		params["geneId"] = r.GeneID
+	}
+	if r.ReferenceBases != "" {
+		params["referenceBases"] = r.ReferenceBases
+	}
+	if r.AlternateBases != "" {
+		params["alternateBases"] = r.AlternateBases
+	}
+	return &RequestSummary{
+		APIVersion:           APIVersion,
+		RequestedSchemas:     variantSchemas,
+		Pagination:           pagination{Skip: r.Skip, Limit: r.Limit},
+		RequestedGranularity: r.Granularity,
+		RequestParameters:    params,
+	}
+}
+
+// InfoResponse is the response of /info endpoint.
+type InfoResponse struct {
+	Meta     Meta  `json:"meta"`
+	Response *Info `json:"response"`
+}
+
+// ErrorResponse reports a failed request.
+type ErrorResponse struct {
+	Meta  Meta  `json:"meta"`
+	Error Error `json:"error"`
+}
+
+// Error is the Beacon error object.
+type Error struct {
+	ErrorCode    int    `json:"errorCode"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+// Response is the response of genomic variants queries.
+type Response struct {
+	Meta            Meta            `json:"meta"`
+	ResponseSummary ResponseSummary `json:"responseSummary"`
+	Response        *ResultSets     `json:"response,omitempty"`
+}
+
+// ResponseSummary tells whether variants were found and how many.
+type ResponseSummary struct {
+	Exists          bool   `json:"exists"`
+	NumTotalResults *int64 `json:"numTotalResults,omitempty"`
+}
+
+// ResultSets groups records by dataset.
+type ResultSets struct {
+	ResultSets []*ResultSet `json:"resultSets"`
+}
+
+// ResultSet contains records of a dataset.
+type ResultSet struct {
+	ID           string              `json:"id"`
+	SetType      string              `json:"setType"`
+	Exists       bool                `json:"exists"`
+	ResultsCount int64               `json:"resultsCount"`
+	Results      []*GenomicVariation `json:"results"`
+}
+
+// GenomicVariation is a record of genomic variation, one per alternate allele.
+type GenomicVariation struct {
+	VariantInternalID      string                  `json:"variantInternalId"`
+	Variation              Variation               `json:"variation"`
+	Identifiers            *Identifiers            `json:"identifiers,omitempty"`
+	MolecularAttributes    *MolecularAttributes    `json:"molecularAttributes,omitempty"`
+	FrequencyInPopulations []FrequencyInPopulation `json:"frequencyInPopulations,omitempty"`
+}
+
+// Variation is the allele and its location.
+type Variation struct {
+	VariantType    string   `json:"variantType,omitempty"`
+	ReferenceBases string   `json:"referenceBases"`
+	AlternateBases string   `json:"alternateBases"`
+	Location       Location `json:"location"`
+}
+
+// Location is a sequence location with 0-based, half-open interval.
+type Location struct {
+	Type       string   `json:"type"`
+	SequenceID string   `json:"sequence_id"`
+	Interval   Interval `json:"interval"`
+}
+
+// Interval is a sequence interval.
+type Interval struct {
+	Type  string `json:"type"`
+	Start Number `json:"start"`
+	End   Number `json:"end"`
+}
+
+// Number is a position in a sequence interval.
+type Number struct {
+	Type  string `json:"type"`
+	Value int64  `json:"value"`
+}
+
+// Identifiers are external variant identifiers.
+type Identifiers struct {
+	VariantAlternativeIds []Identifier `json:"variantAlternativeIds,omitempty"`
+	GenomicHGVSID         string       `json:"genomicHGVSId,omitempty"`
+}
+
+// Identifier is an external identifier, like dbSNP ID.
+type Identifier struct {
+	ID string `json:"id"`
+}
+
+// MolecularAttributes are annotations of the variant.
+type MolecularAttributes struct {
+	GeneIds          []string   `json:"geneIds,omitempty"`
+	MolecularEffects []Ontology `json:"molecularEffects,omitempty"`
+}
+
+// Ontology is an ontology term.
+type Ontology struct {
+	ID    string `json:"id"`
+	Label string `json:"label,omitempty"`
+}
+
+// FrequencyInPopulation is the allele frequency reported by a dataset.
+type FrequencyInPopulation struct {
+	Source          string      `json:"source"`
+	SourceReference string      `json:"sourceReference"`
+	Frequencies     []Frequency `json:"frequencies"`
+}
+
+// Frequency is the allele frequency of a population.
+type Frequency struct {
+	Population      string  `json:"population"`
+	AlleleFrequency float32 `json:"alleleFrequency"`
+}
+
+// NewResponse builds genomic variants response according to granularity.
+// Total is the number of alleles matching request (see Alleles), the unit of results,
+// and variants are the requested page.
+func NewResponse(info *Info, r *Request, total int64, variants []*variant.Variant) *Response {
+	resp := &Response{Meta: NewMeta(info, r), ResponseSummary: ResponseSummary{Exists: total > 0}}
+	if r.Granularity == Boolean {
+		return resp
+	}
+	resp.ResponseSummary.NumTotalResults = &total
+	if r.Granularity == Count {
+		return resp
+	}
+
+	sets := make(map[string]*ResultSet)
+	resp.Response = &ResultSets{ResultSets: []*ResultSet{}}
+	for _, v := range variants {
+		set, ok := sets[v.DatasetID]
+		if !ok {
+			set = &ResultSet{ID: v.DatasetID, SetType: "dataset", Exists: true, Results: []*GenomicVariation{}}
+			sets[v.DatasetID] = set
+			resp.Response.ResultSets = append(resp.Response.ResultSets, set)
+		}
+		for _, i := range r.Alleles(v) {
+			set.Results = append(set.Results, NewGenomicVariation(v, i))
+		}
+		set.ResultsCount = int64(len(set.Results))
+	}
+	return resp
+}
+
+// Alleles returns indices of alternate alleles of variant that match request, each one a Beacon record.
+func (r *Request) Alleles(v *variant.Variant) []int {
+	var indices []int
+	for i, alt := range v.AlternateBases {
+		if r.AlternateBases == "" || alt == r.AlternateBases {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// NewGenomicVariation converts the i-th alternate allele of variant to a Beacon record.
+func NewGenomicVariation(v *variant.Variant, i int) *GenomicVariation {
+	start := int64(v.Start) - 1
+	gv := &GenomicVariation{
+		VariantInternalID: v.AlleleID(i),
+		Variation: Variation{
+			ReferenceBases: v.ReferenceBases,
+			AlternateBases: v.AlternateBases[i],
+			Location: Location{
+				Type:       "SequenceLocation",
+				SequenceID: fmt.Sprintf("%s:%s", v.AssemblyID, v.ReferenceName),
+				Interval: Interval{
+					Type:  "SequenceInterval",
+					Start: Number{Type: "Number", Value: start},
+					End:   Number{Type: "Number", Value: int64(v.Stop())},
+				},
+			},
+		},
+	}
+	if len(v.SnpIds) > 0 {
+		gv.Identifiers = &Identifiers{}
+		for _, id := range v.SnpIds {
+			gv.Identifiers.VariantAlternativeIds = append(gv.Identifiers.VariantAlternativeIds, Identifier{ID: "dbSNP:" + id})
+		}
+	}
+	if i < len(v.GeneSymbol) || i < len(v.Type) {
+		gv.MolecularAttributes = &MolecularAttributes{}
+		if i < len(v.GeneSymbol) && v.GeneSymbol[i] != "" {
+			gv.MolecularAttributes.GeneIds = []string{v.GeneSymbol[i]}
+		}
+		if i < len(v.Type) {
+			gv.MolecularAttributes.MolecularEffects = molecularEffects(v.Type[i])
+		}
+	}
+	if i < len(v.AlleleFrequency) {
+		gv.FrequencyInPopulations = []FrequencyInPopulation{{
+			Source:          v.DatasetID,
+			SourceReference: v.DatasetID,
+			Frequencies:     []Frequency{{Population: v.DatasetID, AlleleFrequency: v.AlleleFrequency[i]}},
+		}}
+	}
+	return gv
+}
+
+// FilteringTermsResponse lists filters accepted by queries.
+// BraVE does not support Beacon filters, so the list is empty.
+type FilteringTermsResponse struct {
+	Meta     Meta `json:"meta"`
+	Response struct {
+		FilteringTerms []Ontology `json:"filteringTerms"`
+	} `json:"response"`
+}
+
+// NewFilteringTermsResponse builds the filtering terms response.
+func NewFilteringTermsResponse(info *Info) *FilteringTermsResponse {
+	resp := &FilteringTermsResponse{Meta: NewMeta(info, nil)}
+	resp.Response.FilteringTerms = []Ontology{}
+	return resp
+}
+
+// ConfigurationResponse describes entry types served by this Beacon.
+type ConfigurationResponse struct {
+	Meta     Meta          `json:"meta"`
+	Response Configuration `json:"response"`
+}
+
+// Configuration is the Beacon configuration.
+type Configuration struct {
+	MaturityAttributes struct {
+		ProductionStatus string `json:"productionStatus"`
+	} `json:"maturityAttributes"`
+	SecurityAttributes struct {
+		DefaultGranularity string   `json:"defaultGranularity"`
+		SecurityLevels     []string `json:"securityLevels"`
+	} `json:"securityAttributes"`
+	EntryTypes map[string]EntryType `json:"entryTypes"`
+}
+
+// EntryType describes an entry type, like genomic variants.
+type EntryType struct {
+	ID                      string        `json:"id"`
+	Name                    string        `json:"name"`
+	OntologyTermForThisType Ontology      `json:"ontologyTermForThisType"`
+	PartOfSpecification     string        `json:"partOfSpecification"`
+	DefaultSchema           DefaultSchema `json:"defaultSchema"`
+}
+
+// DefaultSchema is the schema of entry type records.
+type DefaultSchema struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	SchemaVersion string `json:"schemaVersion"`
+}
+
+// NewConfigurationResponse builds the configuration response.
+func NewConfigurationResponse(info *Info) *ConfigurationResponse {
+	resp := &ConfigurationResponse{Meta: NewMeta(info, nil)}
+	resp.Response.MaturityAttributes.ProductionStatus = "PROD"
+	resp.Response.SecurityAttributes.DefaultGranularity = Record
+	resp.Response.SecurityAttributes.SecurityLevels = []string{"PUBLIC"}
+	resp.Response.EntryTypes = map[string]EntryType{
+		"genomicVariant": {
+			ID:                      "genomicVariant",
+			Name:                    "Genomic Variants",
+			OntologyTermForThisType: Ontology{ID: "ENSGLOSSARY:0000092", Label: "Variant"},
+			PartOfSpecification:     "Beacon " + APIVersion,
+			DefaultSchema: DefaultSchema{
+				ID:            VariantSchema,
+				Name:          "Default schema for a genomic variation",
+				SchemaVersion: APIVersion,
+			},
+		},
+	}
+	return resp
+}
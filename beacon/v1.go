@@ -0,0 +1,217 @@
+package beacon
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/labbcb/brave/search"
+	"github.com/labbcb/brave/variant"
+)
+
+// V1APIVersion is the legacy Beacon specification version implemented by /query endpoint.
+const V1APIVersion = "v1.0.1"
+
+// Values of includeDatasetResponses.
+const (
+	All  = "ALL"
+	Hit  = "HIT"
+	Miss = "MISS"
+	None = "NONE"
+)
+
+// AlleleRequest is a Beacon v1 query. Start is 0-based.
+type AlleleRequest struct {
+	ReferenceName           string   `json:"referenceName"`
+	Start                   int64    `json:"start"`
+	ReferenceBases          string   `json:"referenceBases"`
+	AlternateBases          string   `json:"alternateBases"`
+	AssemblyID              string   `json:"assemblyId"`
+	DatasetIds              []string `json:"datasetIds,omitempty"`
+	IncludeDatasetResponses string   `json:"includeDatasetResponses"`
+}
+
+// ParseAlleleRequest reads a Beacon v1 query from URL query.
+// Dataset IDs may be repeated or comma-separated.
+func ParseAlleleRequest(values url.Values) (*AlleleRequest, error) {
+	r := &AlleleRequest{
+		ReferenceName:           strings.TrimPrefix(values.Get("referenceName"), "chr"),
+		ReferenceBases:          strings.ToUpper(values.Get("referenceBases")),
+		AlternateBases:          strings.ToUpper(values.Get("alternateBases")),
+		AssemblyID:              values.Get("assemblyId"),
+		IncludeDatasetResponses: strings.ToUpper(values.Get("includeDatasetResponses")),
+	}
+	for _, ids := range values["datasetIds"] {
+		for _, id := range strings.Split(ids, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				r.DatasetIds = append(r.DatasetIds, id)
+			}
+		}
+	}
+
+	for key, value := range map[string]string{
+		"referenceName":  r.ReferenceName,
+		"start":          values.Get("start"),
+		"referenceBases": r.ReferenceBases,
+		"alternateBases": r.AlternateBases,
+		"assemblyId":     r.AssemblyID,
+	} {
+		if value == "" {
+			return nil, fmt.Errorf("%s is required", key)
+		}
+	}
+
+	start, err := strconv.ParseInt(values.Get("start"), 10, 32)
+	if err != nil || start < 0 {
+		return nil, fmt.Errorf("invalid start %q", values.Get("start"))
+	}
+	r.Start = start
+
+	switch r.IncludeDatasetResponses {
+	case "":
+		r.IncludeDatasetResponses = None
+	case All, Hit, Miss, None:
+	default:
+		return nil, fmt.Errorf("invalid includeDatasetResponses %q", r.IncludeDatasetResponses)
+	}
+	return r, nil
+}
+
+// Input converts Beacon v1 request to search input of variants with requested allele.
+func (r *AlleleRequest) Input() *search.Input {
+	return &search.Input{Queries: []*search.Query{{
+		AssemblyID:     r.AssemblyID,
+		ReferenceName:  r.ReferenceName,
+		Start:          int32(r.Start + 1),
+		ReferenceBases: r.ReferenceBases,
+		AlternateBases: r.AlternateBases,
+	}}}
+}
+
+// AlleleResponse is the Beacon v1 response.
+type AlleleResponse struct {
+	BeaconID               string                   `json:"beaconId"`
+	APIVersion             string                   `json:"apiVersion"`
+	Exists                 *bool                    `json:"exists"`
+	AlleleRequest          *AlleleRequest           `json:"alleleRequest"`
+	DatasetAlleleResponses []*DatasetAlleleResponse `json:"datasetAlleleResponses,omitempty"`
+	Error                  *Error                   `json:"error"`
+}
+
+// DatasetAlleleResponse reports whether the allele exists in a dataset and its frequency.
+type DatasetAlleleResponse struct {
+	DatasetID    string  `json:"datasetId"`
+	Exists       bool    `json:"exists"`
+	Frequency    float32 `json:"frequency"`
+	VariantCount int64   `json:"variantCount"`
+	CallCount    int64   `json:"callCount"`
+	SampleCount  int64   `json:"sampleCount"`
+	Note         string  `json:"note,omitempty"`
+	ExternalURL  string  `json:"externalUrl,omitempty"`
+}
+
+// NewAlleleResponse builds Beacon v1 response from variants with requested allele.
+// Datasets are the registered datasets reported in dataset responses, in addition to datasets where the allele was found.
+// If request names datasets, only them are considered.
+// Counts are summed over variants of a dataset: calls are allele counts (AC) and samples are
+// heterozygous and homozygous alternate samples. Frequency is pooled AC/AN, or the mean
+// allele frequency if variants don't have allele counts.
+func NewAlleleResponse(info *Info, r *AlleleRequest, datasets []string, variants []*variant.Variant) *AlleleResponse {
+	if len(r.DatasetIds) > 0 {
+		datasets = r.DatasetIds
+	}
+
+	found := make(map[string]*DatasetAlleleResponse)
+	pooled := make(map[string]*alleleCounts)
+	for _, v := range variants {
+		i := r.allele(v)
+		if i < 0 || len(r.DatasetIds) > 0 && !contains(r.DatasetIds, v.DatasetID) {
+			continue
+		}
+		d, ok := found[v.DatasetID]
+		if !ok {
+			d = &DatasetAlleleResponse{DatasetID: v.DatasetID, Exists: true}
+			found[v.DatasetID] = d
+			pooled[v.DatasetID] = new(alleleCounts)
+			if !contains(datasets, v.DatasetID) {
+				datasets = append(datasets, v.DatasetID)
+			}
+		}
+		d.VariantCount++
+		d.CallCount += int64(valueAt(v.AlleleCount, i))
+		d.SampleCount += int64(valueAt(v.HetCount, i) + valueAt(v.HomAltCount, i))
+		pooled[v.DatasetID].add(v, i)
+	}
+	for id, d := range found {
+		d.Frequency = pooled[id].frequency()
+	}
+
+	exists := len(found) > 0
+	resp := &AlleleResponse{BeaconID: info.ID, APIVersion: V1APIVersion, Exists: &exists, AlleleRequest: r}
+	for _, id := range datasets {
+		d, hit := found[id]
+		if !hit {
+			d = &DatasetAlleleResponse{DatasetID: id}
+		}
+		switch {
+		case r.IncludeDatasetResponses == All,
+			r.IncludeDatasetResponses == Hit && hit,
+			r.IncludeDatasetResponses == Miss && !hit:
+			resp.DatasetAlleleResponses = append(resp.DatasetAlleleResponses, d)
+		}
+	}
+	return resp
+}
+
+// alleleCounts pools allele counts and frequencies of an allele over variants.
+type alleleCounts struct {
+	ac, an int
+	af     float64 // sum of frequencies of variants without allele counts
+	n      int     // variants without allele counts
+}
+
+func (c *alleleCounts) add(v *variant.Variant, i int) {
+	if i < len(v.AlleleCount) && v.AlleleNumber > 0 {
+		c.ac += v.AlleleCount[i]
+		c.an += v.AlleleNumber
+	} else if i < len(v.AlleleFrequency) {
+		c.af += float64(v.AlleleFrequency[i])
+		c.n++
+	}
+}
+
+func (c *alleleCounts) frequency() float32 {
+	if c.an > 0 {
+		return float32(float64(c.ac) / float64(c.an))
+	}
+	if c.n > 0 {
+		return float32(c.af / float64(c.n))
+	}
+	return 0
+}
+
+// valueAt returns the i-th value of a list with one value per ALT, or zero if it is missing.
+func valueAt(xs []int, i int) int {
+	if i < len(xs) {
+		return xs[i]
+	}
+	return 0
+}
+
+// allele returns the index of requested alternate allele in variant or -1 if variant does not match.
+func (r *AlleleRequest) allele(v *variant.Variant) int {
+	if v.ReferenceBases != r.ReferenceBases {
+		return -1
+	}
+	return v.AlleleIndex(r.AlternateBases)
+}
+
+func contains(xs []string, s string) bool {
+	for _, x := range xs {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
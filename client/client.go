@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"github.com/labbcb/brave/search"
 	"github.com/labbcb/brave/variant"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"strings"
 )
 
 type Client struct {
@@ -46,39 +48,102 @@ func (c *Client) SearchVariants(input *search.Input) (*search.Response, error) {
 }
 
 // InsertVariant submits a variant to BraVE server.
-func (c *Client) InsertVariant(v *variant.Variant) error {
+// Write mode defines what happens if variant already exists.
+func (c *Client) InsertVariant(v *variant.Variant, mode variant.WriteMode) (*variant.WriteResult, error) {
 	var b bytes.Buffer
 	if err := json.NewEncoder(&b).Encode(v); err != nil {
-		return err
+		return nil, err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, c.Host+"/variants", &b)
+	req, err := http.NewRequest(http.MethodPost, c.Host+"/variants?onConflict="+string(mode), &b)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	req.SetBasicAuth(c.Username, c.Password)
 
 	client := http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusConflict {
+		return conflict(resp.Body)
+	}
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%d: %s", resp.StatusCode, string(body))
+	}
+
+	var res struct {
+		ID string `json:"id"`
+		variant.WriteResult
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+	v.ID = res.ID
+	return &res.WriteResult, nil
+}
+
+// InsertVariants submits a batch of variants to BraVE server as newline-delimited JSON.
+// Write mode defines what happens to variants that already exist.
+func (c *Client) InsertVariants(vs []*variant.Variant, mode variant.WriteMode) (*variant.WriteResult, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	for _, v := range vs {
+		if err := enc.Encode(v); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.Host+"/variants:batch?onConflict="+string(mode), &b)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.SetBasicAuth(c.Username, c.Password)
+
+	client := http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusConflict {
+		return conflict(resp.Body)
+	}
 	if resp.StatusCode != http.StatusCreated {
 		body, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		return fmt.Errorf("%d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("%d: %s", resp.StatusCode, string(body))
 	}
 
-	var res map[string]string
+	var res variant.WriteResult
 	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
-		return err
+		return nil, err
 	}
-	v.ID = res["id"]
-	return nil
+	return &res, nil
+}
+
+// conflict decodes the response to inserts of existing variants: counts of the other variants,
+// which were saved, and an error that matches variant.ErrDuplicate.
+func conflict(body io.Reader) (*variant.WriteResult, error) {
+	var res struct {
+		variant.WriteResult
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(body).Decode(&res); err != nil {
+		return nil, err
+	}
+	return &res.WriteResult, fmt.Errorf("%w%s", variant.ErrDuplicate, strings.TrimPrefix(res.Error, variant.ErrDuplicate.Error()))
 }
 
 func (c *Client) RemoveVariants(datasetID, assemblyID string) error {
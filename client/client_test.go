@@ -0,0 +1,41 @@
+package client
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labbcb/brave/mem"
+	"github.com/labbcb/brave/server"
+	"github.com/labbcb/brave/variant"
+)
+
+func TestInsertDuplicates(t *testing.T) {
+	db := mem.New()
+	ts := httptest.NewServer(server.New(db, "admin", "secret").Router)
+	defer ts.Close()
+	c := &Client{Host: ts.URL, Username: "admin", Password: "secret"}
+
+	newVariant := func(start int32) *variant.Variant {
+		return &variant.Variant{DatasetID: "bipmed", AssemblyID: "hg19", ReferenceName: "1", Start: start, ReferenceBases: "A", AlternateBases: []string{"G"}}
+	}
+	if _, err := c.InsertVariant(newVariant(100), variant.Insert); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := c.InsertVariant(newVariant(100), variant.Insert)
+	if !errors.Is(err, variant.ErrDuplicate) || result == nil || result.Created != 0 {
+		t.Errorf("want duplicate error and no variant created, got %+v, %v", result, err)
+	}
+
+	result, err = c.InsertVariants([]*variant.Variant{newVariant(100), newVariant(200)}, variant.Insert)
+	if !errors.Is(err, variant.ErrDuplicate) || result == nil || result.Created != 1 {
+		t.Errorf("want duplicate error and one variant created, got %+v, %v", result, err)
+	}
+	if err != nil && err.Error() != "duplicate variant: bipmed-hg19-1-100-A-G" {
+		t.Errorf("unexpected error message %q", err)
+	}
+	if n := len(db.Variants()); n != 2 {
+		t.Errorf("want 2 variants, got %d", n)
+	}
+}
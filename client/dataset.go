@@ -0,0 +1,107 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/labbcb/brave/dataset"
+)
+
+// CreateDataset registers a dataset. It returns dataset.ErrExists if dataset ID is taken.
+func (c *Client) CreateDataset(d *dataset.Dataset) error {
+	return c.sendDataset(http.MethodPost, c.Host+"/datasets", d, http.StatusCreated)
+}
+
+// UpdateDataset replaces metadata of a registered dataset.
+func (c *Client) UpdateDataset(d *dataset.Dataset) error {
+	return c.sendDataset(http.MethodPut, c.Host+"/datasets/"+url.PathEscape(d.ID), d, http.StatusOK)
+}
+
+// GetDataset requests a registered dataset. It returns dataset.ErrNotFound if dataset is not registered.
+func (c *Client) GetDataset(id string) (*dataset.Dataset, error) {
+	var d dataset.Dataset
+	if err := c.do(http.MethodGet, c.Host+"/datasets/"+url.PathEscape(id), nil, http.StatusOK, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ListDatasets requests all registered datasets.
+func (c *Client) ListDatasets() ([]*dataset.Dataset, error) {
+	var ds []*dataset.Dataset
+	if err := c.do(http.MethodGet, c.Host+"/datasets", nil, http.StatusOK, &ds); err != nil {
+		return nil, err
+	}
+	return ds, nil
+}
+
+// RemoveDataset unregisters a dataset. Variants of the dataset are not removed.
+func (c *Client) RemoveDataset(id string) error {
+	return c.do(http.MethodDelete, c.Host+"/datasets/"+url.PathEscape(id), nil, http.StatusOK, nil)
+}
+
+// GetStats requests variant statistics per dataset and assembly.
+// Dataset and assembly IDs are optional filters.
+func (c *Client) GetStats(datasetID, assemblyID string) ([]*dataset.Stats, error) {
+	params := url.Values{}
+	params.Set("dataset", datasetID)
+	params.Set("assembly", assemblyID)
+
+	var stats []*dataset.Stats
+	if err := c.do(http.MethodGet, c.Host+"/stats?"+params.Encode(), nil, http.StatusOK, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (c *Client) sendDataset(method, url string, d *dataset.Dataset, status int) error {
+	var b bytes.Buffer
+	if err := json.NewEncoder(&b).Encode(d); err != nil {
+		return err
+	}
+	return c.do(method, url, &b, status, d)
+}
+
+// do sends an authenticated request and decodes JSON response into out, if not nil.
+// Not found and conflict responses are returned as dataset.ErrNotFound and dataset.ErrExists.
+func (c *Client) do(method, url string, body io.Reader, status int, out interface{}) error {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.SetBasicAuth(c.Username, c.Password)
+
+	client := http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case status:
+	case http.StatusNotFound:
+		return dataset.ErrNotFound
+	case http.StatusConflict:
+		return dataset.ErrExists
+	default:
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("%d: %s", resp.StatusCode, string(body))
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
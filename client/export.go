@@ -0,0 +1,95 @@
+package client
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/labbcb/brave/export"
+)
+
+// ExportOptions filters exported variants. All fields are optional.
+type ExportOptions struct {
+	DatasetID  string
+	AssemblyID string
+	Region     string // genomic range like 1:15000-16000
+	Query      string // query expression
+	Format     string // ndjson (default), tsv or vcf
+}
+
+// Export streams variants from BraVE server to w, in position order.
+// Progress, if not nil, is called after each variant with the number of variants written
+// and the total number of variants, or -1 if server did not report it.
+// It returns the number of variants written.
+func (c *Client) Export(opts *ExportOptions, w io.Writer, progress func(n, total int64)) (int64, error) {
+	params := url.Values{}
+	params.Set("dataset", opts.DatasetID)
+	params.Set("assembly", opts.AssemblyID)
+	params.Set("region", opts.Region)
+	params.Set("q", opts.Query)
+	params.Set("format", opts.Format)
+
+	req, err := http.NewRequest(http.MethodGet, c.Host+"/export?"+params.Encode(), nil)
+	if err != nil {
+		return 0, err
+	}
+	req.SetBasicAuth(c.Username, c.Password)
+
+	client := http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return 0, err
+		}
+		return 0, fmt.Errorf("%d: %s", resp.StatusCode, string(body))
+	}
+
+	total, err := strconv.ParseInt(resp.Header.Get("X-Total-Count"), 10, 64)
+	if err != nil {
+		total = -1
+	}
+
+	// every line is a variant except TSV and VCF header lines
+	var n int64
+	header := opts.Format == export.TSV
+	r := bufio.NewReader(resp.Body)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			if _, err := w.Write(line); err != nil {
+				return n, err
+			}
+			if header {
+				header = false
+			} else if opts.Format != export.VCF || line[0] != '#' {
+				n++
+				if progress != nil {
+					progress(n, total)
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+
+	// trailers are available after body is read
+	if msg := resp.Trailer.Get("X-Export-Error"); msg != "" {
+		return n, errors.New(msg)
+	}
+	return n, nil
+}
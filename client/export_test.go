@@ -0,0 +1,43 @@
+package client
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labbcb/brave/mem"
+	"github.com/labbcb/brave/server"
+	"github.com/labbcb/brave/variant"
+)
+
+func TestExport(t *testing.T) {
+	s := server.New(mem.New(), "admin", "secret")
+	for pos := int32(1); pos <= 5; pos++ {
+		v := &variant.Variant{DatasetID: "bipmed", AssemblyID: "hg19", ReferenceName: "1", Start: pos * 10, ReferenceBases: "A", AlternateBases: []string{"G"}}
+		if _, err := s.InsertVariant(v, variant.Insert); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ts := httptest.NewServer(s.Router)
+	defer ts.Close()
+
+	var b bytes.Buffer
+	var last, total int64
+	n, err := (&Client{Host: ts.URL}).Export(&ExportOptions{DatasetID: "bipmed", Region: "1:20-40", Format: "tsv"}, &b, func(n, t int64) {
+		last, total = n, t
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 || last != 3 || total != 3 {
+		t.Errorf("want 3 variants, got %d (progress %d/%d)", n, last, total)
+	}
+	if lines := strings.Count(b.String(), "\n"); lines != 4 {
+		t.Errorf("want header and 3 lines, got %d lines", lines)
+	}
+
+	if _, err := (&Client{Host: ts.URL}).Export(&ExportOptions{Format: "xml"}, &b, nil); err == nil {
+		t.Error("want error for unknown format")
+	}
+}
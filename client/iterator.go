@@ -0,0 +1,70 @@
+package client
+
+import (
+	"github.com/labbcb/brave/search"
+	"github.com/labbcb/brave/variant"
+)
+
+// DefaultPageSize is the number of variants requested per page by Iterator if input length is not set.
+const DefaultPageSize = 1000
+
+// Iterator walks all variants matching a search, page by page, in position order.
+type Iterator struct {
+	c     *Client
+	input search.Input
+	page  []*variant.Variant
+	v     *variant.Variant
+	last  bool
+	err   error
+}
+
+// Iterate returns an iterator over all variants matching input.
+// Results are sorted by position and paged by cursor, so input sort, start and cursor are ignored.
+//
+//	it := c.Iterate(input)
+//	for it.Next() {
+//		v := it.Variant()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+func (c *Client) Iterate(input *search.Input) *Iterator {
+	it := &Iterator{c: c, input: *input}
+	it.input.Sort = search.SortPosition
+	it.input.Order, it.input.Columns = nil, nil
+	it.input.Start, it.input.Cursor = 0, ""
+	if it.input.Length <= 0 {
+		it.input.Length = DefaultPageSize
+	}
+	return it
+}
+
+// Next advances to the next variant, requesting the next page when needed.
+// It returns false when there are no more variants or an error occurred.
+func (it *Iterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.last || it.err != nil {
+			it.v = nil
+			return false
+		}
+		resp, err := it.c.SearchVariants(&it.input)
+		if err != nil {
+			it.err = err
+			continue
+		}
+		it.page = resp.Variants
+		it.input.Cursor = resp.NextCursor
+		it.last = resp.NextCursor == ""
+	}
+	it.v, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Variant returns the current variant.
+func (it *Iterator) Variant() *variant.Variant {
+	return it.v
+}
+
+// Err returns the error that stopped iteration, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labbcb/brave/mem"
+	"github.com/labbcb/brave/search"
+	"github.com/labbcb/brave/server"
+	"github.com/labbcb/brave/variant"
+)
+
+func TestIterate(t *testing.T) {
+	s := server.New(mem.New(), "admin", "secret")
+	var want []int32
+	for pos := int32(1); pos <= 25; pos++ {
+		v := &variant.Variant{DatasetID: "bipmed", AssemblyID: "hg19", ReferenceName: "1", Start: pos * 10, ReferenceBases: "A", AlternateBases: []string{"G"}}
+		if _, err := s.InsertVariant(v, variant.Insert); err != nil {
+			t.Fatal(err)
+		}
+		if pos > 2 {
+			want = append(want, pos*10)
+		}
+	}
+	ts := httptest.NewServer(s.Router)
+	defer ts.Close()
+
+	c := &Client{Host: ts.URL}
+	it := c.Iterate(&search.Input{Length: 4, Queries: []*search.Query{{ReferenceName: "1", Start: 30, End: 1000}}})
+	var got []int32
+	for it.Next() {
+		got = append(got, it.Variant().Start)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	it = (&Client{Host: ts.URL}).Iterate(&search.Input{Q: "af<"})
+	if it.Next() || it.Err() == nil {
+		t.Error("want error for invalid expression")
+	}
+}
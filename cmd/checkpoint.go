@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/labbcb/brave/vcf"
+)
+
+// checkpointFile returns the path of the sidecar file that keeps import progress of a VCF file,
+// next to VCF file or in dir if not empty.
+// Files in dir have a hash of absolute VCF path, dataset and assembly in their names,
+// so VCF files with the same name in different directories don't share checkpoints.
+func checkpointFile(dir, file, datasetID, assemblyID string) (string, error) {
+	if dir == "" {
+		return file + ".checkpoint", nil
+	}
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(abs + "\x00" + datasetID + "\x00" + assemblyID))
+	return filepath.Join(dir, fmt.Sprintf("%s.%x.checkpoint", filepath.Base(file), sum[:4])), nil
+}
+
+// readCheckpoint loads a checkpoint file. It returns nil if file does not exist.
+func readCheckpoint(path string) (*vcf.Checkpoint, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cp vcf.Checkpoint
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+// writeCheckpoint replaces checkpoint file atomically so an interrupted import never leaves it truncated.
+func writeCheckpoint(path string, cp vcf.Checkpoint) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := json.NewEncoder(tmp).Encode(cp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/labbcb/brave/client"
+	"github.com/labbcb/brave/dataset"
+	"github.com/spf13/cobra"
+)
+
+var newDataset dataset.Dataset
+
+func init() {
+	datasetCmd.PersistentFlags().StringVar(&host, "host", "http://localhost:8080", "URL to BraVE server.")
+	datasetCmd.PersistentFlags().StringVar(&username, "username", "admin", "User name.")
+	datasetCmd.PersistentFlags().StringVar(&password, "password", "", "Password.")
+
+	datasetCreateCmd.Flags().StringVar(&newDataset.Description, "description", "", "Dataset description.")
+	datasetCreateCmd.Flags().Int32Var(&newDataset.SampleCount, "samples", 0, "Number of samples.")
+	datasetCreateCmd.Flags().StringVar(&newDataset.Technology, "technology", "", "Sequencing technology (WES, WGS, SNP array).")
+	datasetCreateCmd.Flags().StringVar(&newDataset.Publication, "publication", "", "DOI or citation of dataset publication.")
+	datasetCreateCmd.Flags().StringVar(&newDataset.License, "license", "", "Data usage license.")
+
+	datasetCmd.AddCommand(datasetCreateCmd, datasetListCmd, datasetShowCmd, datasetDeleteCmd)
+	rootCmd.AddCommand(datasetCmd)
+}
+
+var datasetCmd = &cobra.Command{
+	Use:   "dataset",
+	Short: "Manage dataset metadata",
+	Long: `Datasets must be registered before importing variants into them.
+	Deleting a dataset removes only its metadata, see brave help remove to delete its variants.`,
+}
+
+var datasetCreateCmd = &cobra.Command{
+	Use:   "create ID",
+	Short: "Register a dataset",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		newDataset.ID = args[0]
+		if err := newClient().CreateDataset(&newDataset); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var datasetListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List registered datasets",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ds, err := newClient().ListDatasets()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tSAMPLES\tTECHNOLOGY\tCREATED\tDESCRIPTION")
+		for _, d := range ds {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", d.ID, d.SampleCount, d.Technology, d.CreatedAt.Format(time.DateOnly), d.Description)
+		}
+		w.Flush()
+	},
+}
+
+var datasetShowCmd = &cobra.Command{
+	Use:   "show ID",
+	Short: "Show dataset metadata",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		d, err := newClient().GetDataset(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(d); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var datasetDeleteCmd = &cobra.Command{
+	Use:   "delete ID",
+	Short: "Unregister a dataset",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := newClient().RemoveDataset(args[0]); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func newClient() *client.Client {
+	return &client.Client{
+		Host:     host,
+		Username: username,
+		Password: password,
+	}
+}
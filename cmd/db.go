@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/labbcb/brave/mongo"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	dbCmd.PersistentFlags().String("database", "mongodb://localhost:27017", "URL to MongoDB.")
+
+	dbCmd.AddCommand(ensureIndexesCmd)
+	rootCmd.AddCommand(dbCmd)
+}
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage BraVE database",
+}
+
+var ensureIndexesCmd = &cobra.Command{
+	Use:   "ensure-indexes",
+	Short: "Create MongoDB indexes used by searches",
+	Long: `BraVE server creates missing indexes when it starts.
+	This command does the same without starting the server, for example after restoring a database.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		database, err := databaseURL(cmd)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		db, err := mongo.Connect(database, "brave")
+		if err != nil {
+			log.Fatalf("Conneting to MongoDB: %v", err)
+		}
+
+		names, err := db.EnsureIndexes()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, name := range names {
+			fmt.Println(name)
+		}
+	},
+}
+
+// databaseURL returns --database flag value or BRAVE_DATABASE environment variable.
+// Only MongoDB URLs are accepted.
+func databaseURL(cmd *cobra.Command) (string, error) {
+	database, _ := cmd.Flags().GetString("database")
+	if !cmd.Flags().Changed("database") && viper.IsSet("database") {
+		database = viper.GetString("database")
+	}
+	if !strings.HasPrefix(database, "mongodb://") && !strings.HasPrefix(database, "mongodb+srv://") {
+		return "", errors.New("database commands require a MongoDB URL")
+	}
+	return database, nil
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/labbcb/brave/client"
+	"github.com/labbcb/brave/vcf"
+	"github.com/spf13/cobra"
+)
+
+var exportOptions client.ExportOptions
+var output string
+var compress bool
+
+func init() {
+	exportCmd.Flags().StringVar(&host, "host", "http://localhost:8080", "URL to BraVE server.")
+	exportCmd.Flags().StringVar(&exportOptions.DatasetID, "dataset", "", "Dataset name.")
+	exportCmd.Flags().StringVar(&exportOptions.AssemblyID, "assembly", "", "Genome version.")
+	exportCmd.Flags().StringVar(&exportOptions.Region, "region", "", "Genomic range, like 1:15000-16000.")
+	exportCmd.Flags().StringVarP(&exportOptions.Query, "query", "q", "", "Query expression, like 'gene:SCN1A AND af<0.01'.")
+	exportCmd.Flags().StringVar(&exportOptions.Format, "format", "ndjson", "Output format (ndjson, tsv or vcf).")
+	exportCmd.Flags().StringVarP(&output, "output", "o", "", "Output file. Default is standard output.")
+	exportCmd.Flags().BoolVar(&compress, "gzip", false, "Compress output with bgzip (BGZF). Implied by output files ending with .gz.")
+
+	rootCmd.AddCommand(exportCmd)
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export variants to a file",
+	Long: `BraVE streams variants in position order, filtered by dataset, genome version, genomic range
+	and query expression, as newline-delimited JSON, tab-separated values or sites-only VCF.
+	Compressed files are BGZF, like bgzip, so they can be indexed with tabix.
+	Progress is reported to standard error.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		var w io.Writer = os.Stdout
+		if output != "" {
+			f, err := os.Create(output)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer f.Close()
+			w = f
+			compress = compress || strings.HasSuffix(output, ".gz")
+		}
+		var zw *vcf.BGZFWriter
+		if compress {
+			zw = vcf.NewBGZFWriter(w)
+			w = zw
+		}
+
+		var last time.Time
+		progress := func(n, total int64) {
+			if time.Since(last) < time.Second && n != total {
+				return
+			}
+			last = time.Now()
+			if total > 0 {
+				fmt.Fprintf(os.Stderr, "exported %d/%d variants (%.1f%%)\n", n, total, float64(n)*100/float64(total))
+			} else {
+				fmt.Fprintf(os.Stderr, "exported %d variants\n", n)
+			}
+		}
+
+		n, err := newClient().Export(&exportOptions, w, progress)
+		if err != nil {
+			log.Fatalf("exported %d variants: %v", n, err)
+		}
+		if zw != nil {
+			if err := zw.Close(); err != nil {
+				log.Fatal(err)
+			}
+		}
+		fmt.Fprintf(os.Stderr, "exported %d variants\n", n)
+	},
+}
@@ -3,19 +3,26 @@ package cmd
 import (
 	"bufio"
 	"compress/gzip"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"runtime"
+	"strings"
+	"sync"
 
-	"github.com/labbcb/brave/client"
+	"github.com/labbcb/brave/dataset"
 	"github.com/labbcb/brave/variant"
 
 	"github.com/labbcb/brave/vcf"
 	"github.com/spf13/cobra"
 )
 
-var dontFilter, dryRun bool
+var dontFilter, dryRun, ordered, checkpoint, resume, allowUnregistered, splitAlleles bool
+var batchSize, workers, minDP, minGQ int
+var maxMissing float64
+var onConflict, counts, samples, excludeSamples, groupsFile, checkpointDir string
 
 func init() {
 	importCmd.Flags().StringVar(&host, "host", "http://localhost:8080", "URL to BraVE server.")
@@ -32,6 +39,22 @@ func init() {
 
 	importCmd.Flags().BoolVar(&dontFilter, "dont-filter", false, "Don't filter variants by FILTER column.")
 	importCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Just check VCF without connecting to server.")
+	importCmd.Flags().BoolVar(&allowUnregistered, "allow-unregistered", false, "Import variants even if dataset is not registered.")
+	importCmd.Flags().IntVar(&batchSize, "batch-size", 1000, "Number of variants submitted to server per request.")
+	importCmd.Flags().IntVar(&workers, "workers", runtime.NumCPU(), "Number of workers building variants and uploading batches per file.")
+	importCmd.Flags().BoolVar(&ordered, "ordered", true, "Submit variants in the same order as VCF records.")
+	importCmd.Flags().BoolVar(&checkpoint, "checkpoint", true, "Record last imported VCF record in a .checkpoint file next to VCF file (requires --ordered).")
+	importCmd.Flags().StringVar(&checkpointDir, "checkpoint-dir", "", "Directory of .checkpoint files instead of VCF file directory, like when it is read-only.")
+	importCmd.Flags().BoolVar(&resume, "resume", false, "Continue an interrupted import after the record in .checkpoint file.")
+	importCmd.Flags().StringVar(&counts, "counts", vcf.InfoCounts, "Source of allele counts (AC, AN), frequency (AF) and samples with data (NS): info (INFO fields, computed from genotypes if missing) or genotypes (computed from genotypes).")
+	importCmd.Flags().StringVar(&samples, "samples", "", "Aggregate only these samples: file with one sample name per line or comma-separated names.")
+	importCmd.Flags().StringVar(&excludeSamples, "exclude-samples", "", "Don't aggregate these samples: file with one sample name per line or comma-separated names.")
+	importCmd.Flags().StringVar(&groupsFile, "groups", "", "File mapping samples to groups (population, sex): sample name followed by its groups on each line.")
+	importCmd.Flags().BoolVar(&splitAlleles, "split-alleles", false, "Import multi-allelic records as one variant per alternate allele.")
+	importCmd.Flags().IntVar(&minDP, "min-dp", 0, "Mask genotypes with lower read depth (FORMAT/DP) as missing.")
+	importCmd.Flags().IntVar(&minGQ, "min-gq", 0, "Mask genotypes with lower genotype quality (FORMAT/GQ) as missing.")
+	importCmd.Flags().Float64Var(&maxMissing, "max-missing", 1, "Drop variants with a larger fraction of missing genotypes, after masking.")
+	importCmd.Flags().StringVar(&onConflict, "on-conflict", "insert", "What to do with variants that already exist: insert (fail), replace or skip. Default is skip when resuming.")
 
 	rootCmd.AddCommand(importCmd)
 }
@@ -41,16 +64,87 @@ var importCmd = &cobra.Command{
 	Short: "Import genomic variants from VCF files to database",
 	Long: `BraVE importer supports variant data in Variant Call Format (VCF) files.
 	The server should be running, see brave help server.
+	The dataset should be registered, see brave help dataset.
 	Existing variants that have the same dataset and reference genome are not removed by default.
-	If some variant in VCF file has the same Reference Name (chromosome) and Position then it will panic.
-	See brave help remove to delete previous data before importing.`,
+	If some variant already exists (same dataset, genome version, position and alleles) import fails,
+	unless --on-conflict is replace (overwrite existing variant) or skip (keep existing variant).
+	See brave help remove to delete previous data before importing.
+	Multiple files are imported concurrently.
+	While importing, the last record successfully submitted is saved in a sidecar file (VCF file name plus .checkpoint)
+	that is removed when import finishes. If import is interrupted, run the same command with --resume to skip
+	records already imported. Use --checkpoint-dir when VCF files are in a read-only directory; if checkpoint
+	file cannot be written import goes on without it.
+	Allele counts (AC), allele number (AN), frequency (AF), samples with data (NS) and heterozygous and
+	homozygous alternate samples are computed from sample genotypes (GT). By default INFO fields AC, AN, AF
+	and NS, if present, take precedence over computed values; use --counts genotypes when INFO fields are
+	missing or stale, like after subsetting samples.
+	Genotypes with read depth below --min-dp or genotype quality below --min-gq are masked as missing before
+	counts and DP/GQ distributions are computed, and variants with a fraction of missing genotypes above
+	--max-missing are dropped. Masking implies --counts genotypes, unless --counts is given.
+	Use --samples and --exclude-samples to aggregate a subset of samples, like excluding withdrawn-consent
+	or related individuals. Total samples, counts and distributions are computed from the subset,
+	which implies --counts genotypes, unless --counts is given. Unknown sample names make import fail.
+	With --groups, allele count, allele number, allele frequency and homozygous alternate samples are also
+	computed for each group of samples, like populations and sexes.
+	With --split-alleles, multi-allelic records are imported as one variant per alternate allele, whose ID
+	has a single allele, with frequencies, counts and annotations (ANN) of that allele. Genotypes with other
+	alternate alleles count as non-alternate. Don't mix split and unsplit imports of the same dataset.`,
 	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		for _, file := range args {
-			if err := importVcf(file); err != nil {
+		if resume && !cmd.Flags().Changed("on-conflict") {
+			onConflict = string(variant.Skip)
+		}
+		mode, err := variant.ParseWriteMode(onConflict)
+		if err != nil {
+			log.Fatal(err)
+		}
+		include, err := readSamples(samples)
+		if err != nil {
+			log.Fatal(err)
+		}
+		exclude, err := readSamples(excludeSamples)
+		if err != nil {
+			log.Fatal(err)
+		}
+		groups, err := readGroups(groupsFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		// without --counts the source depends on sample subsets and masking (see vcf.Options.CountsSource)
+		if !cmd.Flags().Changed("counts") {
+			counts = ""
+		} else if counts, err = vcf.ParseCounts(counts); err != nil {
+			log.Fatal(err)
+		}
+		if maxMissing < 0 || maxMissing > 1 {
+			log.Fatal("--max-missing must be between 0 and 1")
+		}
+
+		if !dryRun && !allowUnregistered {
+			if _, err := newClient().GetDataset(datasetID); err != nil {
+				if errors.Is(err, dataset.ErrNotFound) {
+					log.Fatalf("Dataset %s is not registered, see brave help dataset or use --allow-unregistered", datasetID)
+				}
 				log.Fatal(err)
 			}
 		}
+
+		errs := make([]error, len(args))
+		var wg sync.WaitGroup
+		for i, file := range args {
+			wg.Add(1)
+			go func(i int, file string) {
+				defer wg.Done()
+				if err := importVcf(file, mode, include, exclude, groups); err != nil {
+					errs[i] = fmt.Errorf("%s: %w", file, err)
+				}
+			}(i, file)
+		}
+		wg.Wait()
+
+		if err := errors.Join(errs...); err != nil {
+			log.Fatal(err)
+		}
 	},
 }
 
@@ -70,45 +164,298 @@ func isGzip(file string) (bool, error) {
 	return b[0] == 31 && b[1] == 139, nil
 }
 
-func importVcf(file string) error {
+// readSamples reads sample names from a file, one per line, or from a comma-separated list if there is no such file.
+func readSamples(value string) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	text := value
+	if b, err := os.ReadFile(value); err == nil {
+		text = strings.ReplaceAll(string(b), "\n", ",")
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var names []string
+	for _, name := range strings.Split(text, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// readGroups reads a sample-to-group mapping file, if any.
+func readGroups(file string) (map[string][]string, error) {
+	if file == "" {
+		return nil, nil
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	groups, err := vcf.ReadGroups(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return groups, nil
+}
+
+// openVcf opens a plain or gzip-compressed VCF file.
+func openVcf(file string) (io.Reader, error) {
 	var r io.Reader
 	r, err := os.Open(file)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	gz, err := isGzip(file)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if gz {
 		r, err = gzip.NewReader(r)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
+	return r, nil
+}
 
-	c := &client.Client{
-		Host:     host,
-		Username: username,
-		Password: password,
+func importVcf(file string, mode variant.WriteMode, include, exclude []string, groups map[string][]string) error {
+	r, err := openVcf(file)
+	if err != nil {
+		return err
 	}
 
-	importVariant := func(v *variant.Variant) error {
-		return c.InsertVariant(v)
-	}
+	c := newClient()
 
+	upload := func(vs []*variant.Variant) (*variant.WriteResult, error) {
+		return c.InsertVariants(vs, mode)
+	}
 	if dryRun {
-		importVariant = func(v *variant.Variant) error { return nil }
+		upload = func(vs []*variant.Variant) (*variant.WriteResult, error) {
+			return &variant.WriteResult{Created: int64(len(vs))}, nil
+		}
 	}
+	u := newUploader(batchSize, workers, upload)
 
-	doFilter := !dontFilter
-	summary, err := vcf.IterateOver(r, doFilter, datasetID, assemblyID, importVariant)
+	opts := vcf.Options{
+		Filter:         !dontFilter,
+		DatasetID:      datasetID,
+		AssemblyID:     assemblyID,
+		Workers:        workers,
+		Ordered:        ordered,
+		Counts:         counts,
+		MinDP:          minDP,
+		MinGQ:          minGQ,
+		MinCallRate:    1 - maxMissing,
+		Samples:        include,
+		ExcludeSamples: exclude,
+		Groups:         groups,
+		SplitAlleles:   splitAlleles,
+	}
 
-	fmt.Println("Total variants:", summary.TotalVariants)
-	if doFilter {
-		fmt.Println("Passed variants:", summary.PassedVariants)
+	cpFile, err := checkpointFile(checkpointDir, file, datasetID, assemblyID)
+	if err != nil {
+		return err
+	}
+	if resume {
+		if !ordered {
+			return errors.New("resuming requires --ordered")
+		}
+		opts.ResumeAfter, err = readCheckpoint(cpFile)
+		if err != nil {
+			return fmt.Errorf("reading checkpoint: %w", err)
+		}
+	}
+	useCheckpoint := checkpoint && ordered && !dryRun
+	var saved bool
+	if useCheckpoint {
+		// a checkpoint that cannot be saved should not fail imports of users who never resume
+		u.commit = func(cp vcf.Checkpoint) error {
+			if err := writeCheckpoint(cpFile, cp); err != nil {
+				log.Printf("%s: not saving checkpoints: %v", file, err)
+				u.commit = nil
+				return nil
+			}
+			saved = true
+			return nil
+		}
 	}
 
+	summary, err := vcf.IterateOver(r, opts, u.add)
+	if uerr := u.close(); uerr != nil && !errors.Is(err, uerr) {
+		err = errors.Join(uerr, err)
+	}
+	if err == nil && useCheckpoint {
+		if rerr := os.Remove(cpFile); rerr != nil && !os.IsNotExist(rerr) {
+			err = rerr
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintln(&b, file)
+	fmt.Fprintln(&b, "Total variants:", summary.TotalVariants)
+	fmt.Fprintln(&b, "Allele counts:", opts.CountsSource())
+	if len(include) > 0 {
+		fmt.Fprintln(&b, "Included samples:", len(include))
+	}
+	if len(exclude) > 0 {
+		fmt.Fprintln(&b, "Excluded samples:", len(exclude))
+	}
+	if len(groups) > 0 {
+		fmt.Fprintln(&b, "Grouped samples:", len(groups))
+	}
+	var thresholds []string
+	if opts.MinDP > 0 {
+		thresholds = append(thresholds, fmt.Sprintf("DP < %d", opts.MinDP))
+	}
+	if opts.MinGQ > 0 {
+		thresholds = append(thresholds, fmt.Sprintf("GQ < %d", opts.MinGQ))
+	}
+	if len(thresholds) > 0 {
+		fmt.Fprintf(&b, "Masked genotypes (%s): %d\n", strings.Join(thresholds, " or "), summary.MaskedGenotypes)
+	}
+	if opts.MinCallRate > 0 {
+		fmt.Fprintf(&b, "Dropped variants (missing > %g): %d\n", maxMissing, summary.LowCallRate)
+	}
+	if opts.SplitAlleles {
+		fmt.Fprintln(&b, "Split multi-allelic variants:", summary.SplitRecords)
+	}
+	if opts.ResumeAfter != nil {
+		fmt.Fprintln(&b, "Skipped variants (resumed):", summary.SkippedVariants)
+	}
+	if opts.Filter {
+		fmt.Fprintln(&b, "Passed variants:", summary.PassedVariants)
+	}
+	fmt.Fprintln(&b, "Created variants:", u.result.Created)
+	fmt.Fprintln(&b, "Replaced variants:", u.result.Replaced)
+	fmt.Fprintln(&b, "Skipped variants (existing):", u.result.Skipped)
+	if err != nil && saved {
+		fmt.Fprintln(&b, "Checkpoint:", cpFile)
+	}
+	fmt.Print(b.String())
+
 	return err
 }
+
+// uploader groups variants in batches and uploads them concurrently,
+// in a stage separated from reading VCF records.
+type uploader struct {
+	size    int
+	upload  func(vs []*variant.Variant) (*variant.WriteResult, error)
+	batch   []*variant.Variant
+	last    vcf.Checkpoint
+	batches int
+	queue   chan batch
+	wg      sync.WaitGroup
+
+	// commit is called, if set, with the last record of every batch
+	// after it and all previous batches were uploaded.
+	commit    func(cp vcf.Checkpoint) error
+	uploaded  map[int]vcf.Checkpoint
+	committed int
+
+	mu     sync.Mutex
+	result variant.WriteResult
+	err    error
+}
+
+// batch is a group of variants numbered in submission order.
+type batch struct {
+	n        int
+	variants []*variant.Variant
+	last     vcf.Checkpoint // last VCF record in batch
+}
+
+func newUploader(size, workers int, upload func(vs []*variant.Variant) (*variant.WriteResult, error)) *uploader {
+	u := &uploader{size: size, upload: upload, queue: make(chan batch, workers), uploaded: make(map[int]vcf.Checkpoint)}
+	for i := 0; i < max(workers, 1); i++ {
+		u.wg.Add(1)
+		go u.run()
+	}
+	return u
+}
+
+// run uploads queued batches until queue is closed.
+// After the first failure remaining batches are discarded.
+func (u *uploader) run() {
+	defer u.wg.Done()
+	for b := range u.queue {
+		if u.failed() != nil {
+			continue
+		}
+
+		result, err := u.upload(b.variants)
+
+		u.mu.Lock()
+		u.result.Add(result)
+		if err != nil && u.err == nil {
+			first, last := b.variants[0], b.variants[len(b.variants)-1]
+			u.err = fmt.Errorf("batch %d (%d variants, %s:%d to %s:%d): %w", b.n, len(b.variants),
+				first.ReferenceName, first.Start, last.ReferenceName, last.Start, err)
+		}
+		if err == nil && u.commit != nil {
+			u.uploaded[b.n] = b.last
+			if cerr := u.advance(); cerr != nil && u.err == nil {
+				u.err = fmt.Errorf("saving checkpoint: %w", cerr)
+			}
+		}
+		u.mu.Unlock()
+	}
+}
+
+// advance commits the last record of contiguous uploaded batches.
+func (u *uploader) advance() error {
+	cp, ok := u.uploaded[u.committed+1]
+	if !ok {
+		return nil
+	}
+	for ok {
+		delete(u.uploaded, u.committed+1)
+		u.committed++
+		if next, found := u.uploaded[u.committed+1]; found {
+			cp = next
+		} else {
+			ok = false
+		}
+	}
+	return u.commit(cp)
+}
+
+func (u *uploader) failed() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.err
+}
+
+// add queues variant read from record n for upload.
+// Variants of the same record (split alleles) are kept in the same batch, so checkpoints are whole records.
+// It returns the upload error, if any, to stop reading VCF.
+func (u *uploader) add(n uint, v *variant.Variant) error {
+	if len(u.batch) >= u.size && n != u.last.Record {
+		u.flush()
+	}
+	u.batch = append(u.batch, v)
+	u.last = vcf.Checkpoint{Record: n, ReferenceName: v.ReferenceName, Start: v.Start}
+	return u.failed()
+}
+
+func (u *uploader) flush() {
+	if len(u.batch) == 0 {
+		return
+	}
+	u.batches++
+	u.queue <- batch{u.batches, u.batch, u.last}
+	u.batch = nil
+}
+
+// close uploads pending variants and waits for all uploads to finish.
+func (u *uploader) close() error {
+	u.flush()
+	close(u.queue)
+	u.wg.Wait()
+	return u.err
+}
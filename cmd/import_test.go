@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/labbcb/brave/vcf"
+)
+
+func TestUploaderAdvance(t *testing.T) {
+	var commits []uint
+	u := &uploader{uploaded: make(map[int]vcf.Checkpoint), commit: func(cp vcf.Checkpoint) error {
+		commits = append(commits, cp.Record)
+		return nil
+	}}
+
+	// batches 1 to 4 end at records 10, 20, 30 and 40 and finish uploading as 3, 2, 1, 4
+	for _, n := range []int{3, 2, 1, 4} {
+		u.uploaded[n] = vcf.Checkpoint{Record: uint(n * 10)}
+		if err := u.advance(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := fmt.Sprint(commits); got != "[30 40]" {
+		t.Errorf("want commits [30 40], got %s", got)
+	}
+	if u.committed != 4 || len(u.uploaded) != 0 {
+		t.Errorf("want 4 committed batches and none pending, got %d and %v", u.committed, u.uploaded)
+	}
+}
+
+func TestCheckpointFile(t *testing.T) {
+	a, err := checkpointFile("/tmp", "a/chr1.vcf.gz", "bipmed", "hg38")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := checkpointFile("/tmp", "b/chr1.vcf.gz", "bipmed", "hg38")
+	c, _ := checkpointFile("/tmp", "a/chr1.vcf.gz", "abraom", "hg38")
+	again, _ := checkpointFile("/tmp", "a/chr1.vcf.gz", "bipmed", "hg38")
+	if a == b || a == c || a != again {
+		t.Errorf("want distinct checkpoints per VCF path and dataset, got %s, %s, %s and %s", a, b, c, again)
+	}
+	if got, _ := checkpointFile("", "a/chr1.vcf.gz", "bipmed", "hg38"); got != "a/chr1.vcf.gz.checkpoint" {
+		t.Errorf("want sidecar next to VCF file, got %s", got)
+	}
+}
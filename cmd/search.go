@@ -7,7 +7,9 @@ import (
 	"github.com/labbcb/brave/client"
 	"github.com/labbcb/brave/search"
 	"github.com/labbcb/brave/variant"
+	"github.com/labbcb/brave/vcf"
 	"github.com/spf13/cobra"
+	"io"
 	"log"
 	"os"
 	"strconv"
@@ -15,12 +17,33 @@ import (
 )
 
 var format string
+var contained bool
+var expression, sortBy string
+var all, bgzip bool
+var pageSize int64
+var minAF, maxAF float32
+var population string
+var types, clinicalSignificance []string
+var minCoverage, minGenotypeQuality float64
 
 func init() {
 	searchCmd.Flags().StringVar(&host, "host", "http://localhost:8080", "URL to BraVE server.")
 	searchCmd.Flags().StringVar(&datasetID, "dataset", "", "Dataset name.")
 	searchCmd.Flags().StringVar(&assemblyID, "assembly", "", "Genome version.")
-	searchCmd.Flags().StringVar(&format, "format", "console", "Output format.")
+	searchCmd.Flags().StringVar(&format, "format", "console", "Output format (console, json, csv or vcf).")
+	searchCmd.Flags().BoolVar(&bgzip, "bgzip", false, "Compress output with bgzip (BGZF).")
+	searchCmd.Flags().StringVarP(&expression, "query", "q", "", "Query expression, like 'gene:SCN1A AND af<0.01 AND (type:missense_variant OR clnsig:Pathogenic)'.")
+	searchCmd.Flags().StringVar(&sortBy, "sort", "", "Sort keys (position, af, gene, sampleCount, coverage), comma-separated, prefixed with - for descending order.")
+	searchCmd.Flags().BoolVar(&all, "all", false, "Fetch all results page by page, in position order.")
+	searchCmd.Flags().Int64Var(&pageSize, "page-size", client.DefaultPageSize, "Number of variants requested per page with --all.")
+	searchCmd.Flags().BoolVar(&contained, "contained", false, "Genomic ranges match only variants fully inside them.")
+	searchCmd.Flags().Float32Var(&minAF, "min-af", 0, "Minimum allele frequency of any alternate allele.")
+	searchCmd.Flags().Float32Var(&maxAF, "max-af", 0, "Maximum allele frequency of any alternate allele.")
+	searchCmd.Flags().StringVar(&population, "population", "", "Group of samples (AFR, female) whose allele frequency is filtered by --min-af and --max-af.")
+	searchCmd.Flags().StringSliceVar(&types, "type", nil, "Variant types (missense_variant), any of them.")
+	searchCmd.Flags().StringSliceVar(&clinicalSignificance, "clnsig", nil, "Clinical significance terms (Pathogenic), any of them.")
+	searchCmd.Flags().Float64Var(&minCoverage, "min-coverage", 0, "Minimum median coverage (DP).")
+	searchCmd.Flags().Float64Var(&minGenotypeQuality, "min-gq", 0, "Minimum median genotype quality (GQ).")
 
 	rootCmd.AddCommand(searchCmd)
 }
@@ -30,84 +53,246 @@ var searchCmd = &cobra.Command{
 	Short: "Search for variants using queries",
 	Long: `BraVE supports multiple types of queries:
 	Gene symbol (SCN1A) returns variants that were annotated with a matching gene name.
-	Genomic range (1:15000-16000) returns variants that are inside the range (1-based, half-open).
+	Genomic range (1:15000-16000) returns variants that overlap the range (1-based, inclusive),
+	or variants fully inside it with --contained. Variant end is the last base of REF or END/SVLEN of structural variants.
 	Genomic position (1:12345) returns a single variant that have the same position (1-based)
-	dbSNP ID (rs12345) returns a single variant that were annotated with this identifier.`,
+	Genomic allele (1:12345:A>G or 1-12345-A-G) returns variants at the position with the same reference allele
+	and the alternate allele among their alternate alleles.
+	dbSNP ID (rs12345) returns a single variant that were annotated with this identifier.
+	Filters (--min-af, --max-af, --type, --clnsig, --min-coverage, --min-gq) apply to every query,
+	or to all variants if no query is given. With --population allele frequency filters apply to
+	that group of samples instead of all samples, and require --dataset, a genomic position or range,
+	a gene or a dbSNP ID.
+	Query expression (--query) combines terms with NOT, AND, OR and parentheses. Terms are
+	field:value (gene, id, dataset, assembly, region, ref, alt, type, clnsig), numeric comparisons
+	(af, dp and gq with <, <=, >, >= or =; af.AFR for a group of samples) or any of the query types above. Variants must satisfy
+	the expression and at least one of the queries, if any.
+	VCF output (--format vcf) has sites-only records sorted by position.
+	With --all variants are written as pages arrive and VCF contigs are the chromosomes of the queried dataset and assembly.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		var qs []*search.Query
 		for _, text := range args {
-			q := search.Parse(text)
+			qs = append(qs, search.Parse(text))
+		}
+		if len(qs) == 0 && (minAF != 0 || maxAF != 0 || len(types) > 0 || len(clinicalSignificance) > 0 || minCoverage != 0 || minGenotypeQuality != 0) {
+			qs = append(qs, new(search.Query))
+		}
+		for _, q := range qs {
 			q.DatasetID = datasetID
 			q.AssemblyID = assemblyID
-			qs = append(qs, q)
+			q.Contained = contained
+			q.MinAlleleFrequency = minAF
+			q.MaxAlleleFrequency = maxAF
+			q.Population = population
+			q.Types = types
+			q.ClinicalSignificance = clinicalSignificance
+			q.MinCoverage = minCoverage
+			q.MinGenotypeQuality = minGenotypeQuality
 		}
 
-		c := &client.Client{Host: host}
-		resp, err := c.SearchVariants(&search.Input{Queries: qs})
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		switch format {
-		case "json":
-			if err := json.NewEncoder(os.Stdout).Encode(resp.Variants); err != nil {
+		if expression != "" {
+			if _, err := search.ParseExpr(expression); err != nil {
 				log.Fatal(err)
 			}
-		case "csv":
-			if len(resp.Variants) == 0 {
-				return
-			}
+		}
 
-			w := csv.NewWriter(os.Stdout)
-
-			h := []string{
-				"dataset",
-				"assembly",
-				"ns",
-				"total",
-				"chrom",
-				"pos",
-				"id",
-				"ref",
-				"alt",
-				"af",
-				"dp",
-				"gq",
-				"gene",
-			}
-			if err := w.Write(h); err != nil {
-				log.Fatal(err)
-			}
+		var out io.Writer = os.Stdout
+		if bgzip {
+			zw := vcf.NewBGZFWriter(os.Stdout)
+			defer func() {
+				if err := zw.Close(); err != nil {
+					log.Fatal(err)
+				}
+			}()
+			out = zw
+		}
 
-			for _, v := range resp.Variants {
-				s := []string{
-					v.DatasetID,
-					v.AssemblyID,
-					strconv.Itoa(v.SampleCount),
-					strconv.Itoa(int(v.TotalSamples)),
-					v.ReferenceName,
-					strconv.Itoa(int(v.Start)),
-					strings.Join(v.SnpIds, ";"),
-					v.ReferenceBases,
-					strings.Join(v.AlternateBases, ";"),
-					joinFloats(v.AlleleFrequency, ";"),
-					joinDistribution(v.Coverage),
-					joinDistribution(v.GenotypeQuality),
-					strings.Join(v.GeneSymbol, ";"),
+		c := &client.Client{Host: host}
+		input := &search.Input{Queries: qs, Q: expression, Sort: sortBy}
+		if all {
+			if sortBy != "" && sortBy != search.SortPosition {
+				log.Fatal("--all returns variants in position order, it can't be used with --sort")
+			}
+			// variants are written as pages arrive, so VCF contigs are the chromosomes of the queried datasets
+			var contigs []string
+			if format == "vcf" {
+				var err error
+				if contigs, err = statsContigs(c); err != nil {
+					log.Fatal(err)
 				}
-				if err := w.Write(s); err != nil {
+			}
+			w := newSearchWriter(out, format, contigs)
+			input.Length = pageSize
+			it := c.Iterate(input)
+			for it.Next() {
+				if err := w.Write(it.Variant()); err != nil {
 					log.Fatal(err)
 				}
 			}
-			w.Flush()
-		default:
-			for _, v := range resp.Variants {
-				fmt.Println(v)
+			if err := it.Err(); err != nil {
+				log.Fatal(err)
 			}
+			if err := w.Flush(); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+
+		resp, err := c.SearchVariants(input)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if format == "vcf" {
+			vcf.SortVariants(resp.Variants)
+		}
+		w := newSearchWriter(out, format, vcf.Contigs(resp.Variants))
+		for _, v := range resp.Variants {
+			if err := w.Write(v); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if err := w.Flush(); err != nil {
+			log.Fatal(err)
 		}
 	},
 }
 
+// statsContigs returns chromosomes with variants of dataset and assembly flags, in natural chromosome order.
+func statsContigs(c *client.Client) ([]string, error) {
+	stats, err := c.GetStats(datasetID, assemblyID)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var contigs []string
+	for _, st := range stats {
+		for name := range st.Chromosomes {
+			if !seen[name] {
+				seen[name] = true
+				contigs = append(contigs, name)
+			}
+		}
+	}
+	vcf.SortContigs(contigs)
+	return contigs, nil
+}
+
+// searchWriter writes search results one variant at a time.
+type searchWriter interface {
+	Write(v *variant.Variant) error
+	Flush() error
+}
+
+// newSearchWriter creates a writer of search output format. VCF header lists contigs.
+func newSearchWriter(out io.Writer, format string, contigs []string) searchWriter {
+	switch format {
+	case "vcf":
+		return vcf.NewWriter(out, &vcf.Header{DatasetID: datasetID, AssemblyID: assemblyID, Contigs: contigs})
+	case "json":
+		return &jsonArrayWriter{w: out}
+	case "csv":
+		return &csvWriter{w: csv.NewWriter(out)}
+	default:
+		return &consoleWriter{w: out}
+	}
+}
+
+// jsonArrayWriter writes variants as elements of a JSON array.
+type jsonArrayWriter struct {
+	w io.Writer
+	n int
+}
+
+func (w *jsonArrayWriter) Write(v *variant.Variant) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	sep := ","
+	if w.n == 0 {
+		sep = "["
+	}
+	w.n++
+	if _, err := io.WriteString(w.w, sep); err != nil {
+		return err
+	}
+	_, err = w.w.Write(b)
+	return err
+}
+
+func (w *jsonArrayWriter) Flush() error {
+	end := "]\n"
+	if w.n == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(w.w, end)
+	return err
+}
+
+// csvWriter writes a header line before the first variant, nothing if there are no variants.
+type csvWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func (w *csvWriter) Write(v *variant.Variant) error {
+	if !w.header {
+		w.header = true
+		h := []string{
+			"dataset",
+			"assembly",
+			"ns",
+			"total",
+			"chrom",
+			"pos",
+			"id",
+			"ref",
+			"alt",
+			"af",
+			"dp",
+			"gq",
+			"gene",
+		}
+		if err := w.w.Write(h); err != nil {
+			return err
+		}
+	}
+
+	return w.w.Write([]string{
+		v.DatasetID,
+		v.AssemblyID,
+		strconv.Itoa(v.SampleCount),
+		strconv.Itoa(int(v.TotalSamples)),
+		v.ReferenceName,
+		strconv.Itoa(int(v.Start)),
+		strings.Join(v.SnpIds, ";"),
+		v.ReferenceBases,
+		strings.Join(v.AlternateBases, ";"),
+		joinFloats(v.AlleleFrequency, ";"),
+		joinDistribution(v.Coverage),
+		joinDistribution(v.GenotypeQuality),
+		strings.Join(v.GeneSymbol, ";"),
+	})
+}
+
+func (w *csvWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type consoleWriter struct {
+	w io.Writer
+}
+
+func (w *consoleWriter) Write(v *variant.Variant) error {
+	_, err := fmt.Fprintln(w.w, v)
+	return err
+}
+
+func (w *consoleWriter) Flush() error {
+	return nil
+}
+
 func joinFloats(fs []float32, sep string) string {
 	var a []string
 	for _, f := range fs {
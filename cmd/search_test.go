@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labbcb/brave/client"
+	"github.com/labbcb/brave/mem"
+	"github.com/labbcb/brave/server"
+	"github.com/labbcb/brave/variant"
+)
+
+func TestJSONArrayWriter(t *testing.T) {
+	vs := []*variant.Variant{
+		{ReferenceName: "1", Start: 100, ReferenceBases: "A", AlternateBases: []string{"G"}},
+		{ReferenceName: "2", Start: 200, ReferenceBases: "C", AlternateBases: []string{"T"}},
+	}
+	for _, n := range []int{0, 1, 2} {
+		var want bytes.Buffer
+		if err := json.NewEncoder(&want).Encode(append([]*variant.Variant{}, vs[:n]...)); err != nil {
+			t.Fatal(err)
+		}
+
+		var got bytes.Buffer
+		w := &jsonArrayWriter{w: &got}
+		for _, v := range vs[:n] {
+			if err := w.Write(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != want.String() {
+			t.Errorf("%d variants: want %s, got %s", n, want.String(), got.String())
+		}
+	}
+}
+
+func TestStatsContigs(t *testing.T) {
+	db := mem.New()
+	for _, v := range []*variant.Variant{
+		{ID: "a", DatasetID: "bipmed", AssemblyID: "hg19", ReferenceName: "X", Start: 100},
+		{ID: "b", DatasetID: "bipmed", AssemblyID: "hg19", ReferenceName: "10", Start: 100},
+		{ID: "c", DatasetID: "bipmed", AssemblyID: "hg19", ReferenceName: "2", Start: 100},
+		{ID: "d", DatasetID: "abraom", AssemblyID: "hg19", ReferenceName: "1", Start: 100},
+	} {
+		if _, err := db.Save(v, variant.Insert); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ts := httptest.NewServer(server.New(db, "admin", "secret").Router)
+	defer ts.Close()
+
+	datasetID, assemblyID = "bipmed", "hg19"
+	defer func() { datasetID, assemblyID = "", "" }()
+	contigs, err := statsContigs(&client.Client{Host: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(contigs); got != "[2 10 X]" {
+		t.Errorf("want contigs [2 10 X], got %s", got)
+	}
+}
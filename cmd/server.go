@@ -1,18 +1,26 @@
 package cmd
 
 import (
-	"github.com/rs/cors"
-	"github.com/spf13/viper"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/rs/cors"
+	"github.com/spf13/viper"
 
+	"github.com/labbcb/brave/filedb"
+	"github.com/labbcb/brave/mem"
 	"github.com/labbcb/brave/mongo"
 	"github.com/labbcb/brave/server"
+	"github.com/labbcb/brave/variant"
+	"github.com/labbcb/brave/vcf"
 	"github.com/spf13/cobra"
 )
 
 func init() {
-	serverCmd.Flags().String("database", "mongodb://localhost:27017", "URL to MongoDB")
+	serverCmd.Flags().String("database", "mongodb://localhost:27017", "URL to MongoDB, file:// path to embedded database or mem:// for in-memory database.")
 	viper.BindPFlag("database", serverCmd.Flags().Lookup("database"))
 
 	serverCmd.Flags().String("address", ":8080", "Address to bind server.")
@@ -30,19 +38,87 @@ func init() {
 var serverCmd = &cobra.Command{
 	Use:   "server",
 	Short: "Start BraVE server",
-	Long:  `BraVE server requires a MongoDB instance to store genomics variants.`,
+	Long: `BraVE server requires a MongoDB instance to store genomics variants.
+	For small deployments use an embedded database file instead, for example --database file:///var/lib/brave/brave.db
+	For tests and demos use an in-memory database, optionally loaded from a VCF file,
+	for example --database 'mem://?vcf=testdata/test.ann.vcf.gz&dataset=demo&assembly=GRCh37'`,
 	Run: func(cmd *cobra.Command, args []string) {
 		database := viper.GetString("database")
-		db, err := mongo.Connect(database, "brave")
+		db, err := openDatabase(database)
 		if err != nil {
-			log.Fatalf("Conneting to MongoDB: %v", err)
+			log.Fatalf("Opening database %s: %v", database, err)
 		}
 
 		username := viper.GetString("username")
 		password := viper.GetString("password")
 		s := server.New(db, username, password)
+		if err := preload(s, database); err != nil {
+			log.Fatalf("Loading variants into database: %v", err)
+		}
 
 		address := viper.GetString("address")
 		log.Fatal(http.ListenAndServe(address, cors.Default().Handler(s.Router)))
 	},
 }
+
+// openDatabase selects storage backend by URL scheme.
+// file:// opens an embedded database file, mem:// creates an in-memory database,
+// otherwise it connects to MongoDB and ensures that indexes exist.
+func openDatabase(database string) (server.Store, error) {
+	switch {
+	case strings.HasPrefix(database, "file://"):
+		return filedb.Open(strings.TrimPrefix(database, "file://"))
+	case strings.HasPrefix(database, "mem://"):
+		return mem.New(), nil
+	default:
+		db, err := mongo.Connect(database, "brave")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := db.EnsureIndexes(); err != nil {
+			return nil, fmt.Errorf("creating indexes: %w", err)
+		}
+		return db, nil
+	}
+}
+
+// preload imports variants from the VCF file given as vcf parameter of an in-memory database URL.
+// Parameters dataset and assembly default to demo.
+func preload(s *server.Server, database string) error {
+	if !strings.HasPrefix(database, "mem://") {
+		return nil
+	}
+	u, err := url.Parse(database)
+	if err != nil {
+		return err
+	}
+
+	params := u.Query()
+	file := params.Get("vcf")
+	if file == "" {
+		return nil
+	}
+	dataset, assembly := params.Get("dataset"), params.Get("assembly")
+	if dataset == "" {
+		dataset = "demo"
+	}
+	if assembly == "" {
+		assembly = "demo"
+	}
+
+	r, err := openVcf(file)
+	if err != nil {
+		return err
+	}
+	opts := vcf.Options{Filter: true, DatasetID: dataset, AssemblyID: assembly}
+	summary, err := vcf.IterateOver(r, opts, func(n uint, v *variant.Variant) error {
+		_, err := s.InsertVariant(v, variant.Insert)
+		return err
+	})
+	if err != nil {
+		// malformed records are reported but do not prevent serving loaded variants
+		log.Printf("Reading %s: %v", file, err)
+	}
+	log.Printf("Loaded %d of %d variants from %s", summary.PassedVariants, summary.TotalVariants, file)
+	return nil
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	statsCmd.Flags().StringVar(&host, "host", "http://localhost:8080", "URL to BraVE server.")
+	statsCmd.Flags().StringVar(&datasetID, "dataset", "", "Dataset name.")
+	statsCmd.Flags().StringVar(&assemblyID, "assembly", "", "Genome version.")
+	statsCmd.Flags().StringVar(&format, "format", "console", "Output format (console or json).")
+
+	rootCmd.AddCommand(statsCmd)
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show datasets and number of variants",
+	Long: `BraVE summarizes variants of each dataset and genome version:
+	number of variants, total samples, number of variants with clinical significance (CLNSIG),
+	and number of variants per chromosome and per variant type.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		stats, err := newClient().GetStats(datasetID, assemblyID)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if format == "json" {
+			if err := json.NewEncoder(os.Stdout).Encode(stats); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+
+		for _, s := range stats {
+			fmt.Println(s.DatasetID, s.AssemblyID)
+			fmt.Println("  Variants:", s.Variants)
+			fmt.Println("  Total samples:", s.TotalSamples)
+			fmt.Println("  Clinical significance:", s.ClinVar)
+			fmt.Println("  Chromosomes:", joinCounts(s.Chromosomes))
+			fmt.Println("  Types:", joinCounts(s.Types))
+		}
+	},
+}
+
+// joinCounts formats counts as key=count sorted by key.
+func joinCounts(counts map[string]int64) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var a []string
+	for _, k := range keys {
+		a = append(a, fmt.Sprintf("%s=%d", k, counts[k]))
+	}
+	return strings.Join(a, " ")
+}
@@ -0,0 +1,35 @@
+package dataset
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when there is no dataset with the given ID.
+	ErrNotFound = errors.New("dataset not found")
+	// ErrExists is returned when creating a dataset with an ID already registered.
+	ErrExists = errors.New("dataset already exists")
+)
+
+// Dataset is the metadata of a call set. Variants refer to it by DatasetID.
+type Dataset struct {
+	ID          string    `json:"id" bson:"_id"`                                      // dataset ID (bipmed-wes-phase2)
+	Description string    `json:"description,omitempty" bson:"description,omitempty"` // free text description
+	SampleCount int32     `json:"sampleCount,omitempty" bson:"sampleCount,omitempty"` // number of sequenced samples
+	Technology  string    `json:"technology,omitempty" bson:"technology,omitempty"`   // sequencing technology (WES, WGS, SNP array)
+	Publication string    `json:"publication,omitempty" bson:"publication,omitempty"` // DOI or citation of dataset publication
+	License     string    `json:"license,omitempty" bson:"license,omitempty"`         // data usage license
+	CreatedAt   time.Time `json:"createdAt" bson:"createdAt"`                         // registration date
+}
+
+// Stats summarizes variants of a dataset and assembly.
+type Stats struct {
+	DatasetID    string           `json:"datasetId"`    // dataset ID
+	AssemblyID   string           `json:"assemblyId"`   // reference genome version
+	Variants     int64            `json:"variants"`     // number of variants
+	TotalSamples int32            `json:"totalSamples"` // total samples in dataset, as stored in variants
+	ClinVar      int64            `json:"clinvar"`      // number of variants with clinical significance (CLNSIG)
+	Chromosomes  map[string]int64 `json:"chromosomes"`  // number of variants per reference name
+	Types        map[string]int64 `json:"types"`        // number of variants annotated with each variant type
+}
@@ -0,0 +1,164 @@
+// Package export writes variants in bulk formats.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/labbcb/brave/variant"
+	"github.com/labbcb/brave/vcf"
+)
+
+// Export formats.
+const (
+	NDJSON = "ndjson" // newline-delimited JSON, one variant per line
+	TSV    = "tsv"    // tab-separated values with a header line
+	VCF    = "vcf"    // sites-only VCF, variants must be in position order
+)
+
+// Writer writes variants in an export format.
+type Writer interface {
+	// Write writes a variant.
+	Write(v *variant.Variant) error
+	// Flush writes buffered data to the underlying writer.
+	Flush() error
+}
+
+// NewWriter creates a writer of the given format. Empty format means NDJSON.
+// VCF header h may be nil, other formats ignore it.
+func NewWriter(w io.Writer, format string, h *vcf.Header) (Writer, error) {
+	switch format {
+	case "", NDJSON:
+		return &ndjsonWriter{enc: json.NewEncoder(w)}, nil
+	case TSV:
+		cw := csv.NewWriter(w)
+		cw.Comma = '\t'
+		return &tsvWriter{w: cw}, nil
+	case VCF:
+		return vcf.NewWriter(w, h), nil
+	}
+	return nil, fmt.Errorf("unknown export format %q", format)
+}
+
+// ContentType returns the media type of format.
+func ContentType(format string) string {
+	switch format {
+	case TSV:
+		return "text/tab-separated-values"
+	case VCF:
+		return "text/x-vcf"
+	}
+	return "application/x-ndjson"
+}
+
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+func (w *ndjsonWriter) Write(v *variant.Variant) error {
+	return w.enc.Encode(v)
+}
+
+func (w *ndjsonWriter) Flush() error {
+	return nil
+}
+
+// TSVHeader lists columns of TSV export. Multiple values are separated by semicolons.
+var TSVHeader = []string{
+	"dataset",
+	"assembly",
+	"chrom",
+	"pos",
+	"end",
+	"id",
+	"ref",
+	"alt",
+	"af",
+	"ns",
+	"total",
+	"gene",
+	"type",
+	"clnsig",
+	"hgvs",
+	"dp_median",
+	"gq_median",
+	"ac",
+	"an",
+	"het",
+	"hom_alt",
+}
+
+type tsvWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+// writeHeader writes header line once, even if there are no variants.
+func (w *tsvWriter) writeHeader() error {
+	if w.header {
+		return nil
+	}
+	w.header = true
+	return w.w.Write(TSVHeader)
+}
+
+func (w *tsvWriter) Write(v *variant.Variant) error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+
+	var afs []string
+	for _, af := range v.AlleleFrequency {
+		afs = append(afs, strconv.FormatFloat(float64(af), 'g', -1, 32))
+	}
+	return w.w.Write([]string{
+		v.DatasetID,
+		v.AssemblyID,
+		v.ReferenceName,
+		strconv.Itoa(int(v.Start)),
+		strconv.Itoa(int(v.Stop())),
+		strings.Join(v.SnpIds, ";"),
+		v.ReferenceBases,
+		strings.Join(v.AlternateBases, ";"),
+		strings.Join(afs, ";"),
+		strconv.Itoa(v.SampleCount),
+		strconv.Itoa(int(v.TotalSamples)),
+		strings.Join(v.GeneSymbol, ";"),
+		strings.Join(v.Type, ";"),
+		v.CLNSIG,
+		strings.Join(v.HGVS, ";"),
+		median(v.Coverage),
+		median(v.GenotypeQuality),
+		joinInts(v.AlleleCount),
+		strconv.Itoa(v.AlleleNumber),
+		joinInts(v.HetCount),
+		joinInts(v.HomAltCount),
+	})
+}
+
+func (w *tsvWriter) Flush() error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func joinInts(xs []int) string {
+	var a []string
+	for _, x := range xs {
+		a = append(a, strconv.Itoa(x))
+	}
+	return strings.Join(a, ";")
+}
+
+func median(d *variant.Distribution) string {
+	if d == nil {
+		return ""
+	}
+	return strconv.FormatFloat(d.Median, 'g', -1, 64)
+}
@@ -0,0 +1,264 @@
+package filedb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/labbcb/brave/dataset"
+	"github.com/labbcb/brave/mem"
+	"github.com/labbcb/brave/variant"
+)
+
+// DB is an embedded variant store persisted to a single file.
+// Every change is appended to the file as a JSON line; the file is compacted when opened.
+// Variants are kept and queried in memory, so it is meant for small deployments.
+type DB struct {
+	*mem.DB
+	mu   sync.Mutex
+	file *os.File
+}
+
+// entry is a line of the database file.
+type entry struct {
+	Save          *variant.Variant `json:"save,omitempty"`
+	Remove        *removal         `json:"remove,omitempty"`
+	SaveDataset   *dataset.Dataset `json:"saveDataset,omitempty"`
+	RemoveDataset string           `json:"removeDataset,omitempty"`
+}
+
+type removal struct {
+	DatasetID  string `json:"datasetId"`
+	AssemblyID string `json:"assemblyId"`
+}
+
+// Open loads the database file, creating it if it does not exist.
+func Open(path string) (*DB, error) {
+	db := &DB{DB: mem.New()}
+
+	if err := db.load(path); err != nil {
+		return nil, err
+	}
+	if err := db.compact(path); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	db.file = f
+	return db, nil
+}
+
+// Close closes the database file.
+func (db *DB) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.file.Close()
+}
+
+// load replays database file entries.
+// A last line that can't be decoded was torn by a crash while appending, it is dropped
+// and removed from file when it is compacted. Invalid lines before it are errors.
+func (db *DB) load(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for n := 1; ; n++ {
+		line, err := r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			var e entry
+			if err := json.Unmarshal(line, &e); err != nil {
+				if _, peek := r.Peek(1); peek == io.EOF {
+					log.Printf("%s: dropping %d bytes of incomplete last line %d: %v", path, len(line), n, err)
+					return nil
+				}
+				return fmt.Errorf("reading %s line %d: %w", path, n, err)
+			}
+			if err := db.replay(e); err != nil {
+				return fmt.Errorf("reading %s line %d: %w", path, n, err)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// replay applies a database file entry to memory.
+func (db *DB) replay(e entry) error {
+	if e.Save != nil {
+		if _, err := db.DB.Save(e.Save, variant.Replace); err != nil {
+			return err
+		}
+	}
+	if e.Remove != nil {
+		if err := db.DB.Remove(e.Remove.DatasetID, e.Remove.AssemblyID); err != nil {
+			return err
+		}
+	}
+	if e.SaveDataset != nil {
+		err := db.DB.UpdateDataset(e.SaveDataset)
+		if err == dataset.ErrNotFound {
+			err = db.DB.SaveDataset(e.SaveDataset)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if e.RemoveDataset != "" {
+		if err := db.DB.RemoveDataset(e.RemoveDataset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compact rewrites database file with only current variants.
+func (db *DB) compact(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	datasets, err := db.ListDatasets()
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	for _, d := range datasets {
+		if err := enc.Encode(entry{SaveDataset: d}); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	for _, v := range db.Variants() {
+		if err := enc.Encode(entry{Save: v}); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// append writes an entry to the end of database file and syncs it to disk.
+func (db *DB) append(e entry) error {
+	if err := json.NewEncoder(db.file).Encode(e); err != nil {
+		return err
+	}
+	return db.file.Sync()
+}
+
+// Save stores variant to database file according to write mode.
+func (db *DB) Save(v *variant.Variant, mode variant.WriteMode) (*variant.WriteResult, error) {
+	return db.SaveMany([]*variant.Variant{v}, mode)
+}
+
+// SaveMany stores a batch of variants to database file according to write mode.
+// In insert mode duplicate variants are not stored and reported as variant.ErrDuplicate (see mem.DB.SaveMany).
+func (db *DB) SaveMany(vs []*variant.Variant, mode variant.WriteMode) (*variant.WriteResult, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	// later entries replace earlier ones when loading, so skipped and duplicate variants are not written
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	seen := make(map[string]bool, len(vs))
+	for _, v := range vs {
+		if mode != variant.Replace && (seen[v.ID] || db.Has(v.ID)) {
+			continue
+		}
+		seen[v.ID] = true
+		if err := enc.Encode(entry{Save: v}); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := db.file.Write(b.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := db.file.Sync(); err != nil {
+		return nil, err
+	}
+	return db.DB.SaveMany(vs, mode)
+}
+
+// Remove removes variants from database given a dataset ID and/or assembly ID.
+// If both are zero value them it deletes all variants.
+func (db *DB) Remove(datasetID string, assemblyID string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := db.append(entry{Remove: &removal{DatasetID: datasetID, AssemblyID: assemblyID}}); err != nil {
+		return err
+	}
+	return db.DB.Remove(datasetID, assemblyID)
+}
+
+// SaveDataset registers a dataset. It fails if dataset ID already exists.
+func (db *DB) SaveDataset(d *dataset.Dataset) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, err := db.GetDataset(d.ID); err == nil {
+		return dataset.ErrExists
+	}
+	if err := db.append(entry{SaveDataset: d}); err != nil {
+		return err
+	}
+	return db.DB.SaveDataset(d)
+}
+
+// UpdateDataset replaces metadata of a registered dataset.
+func (db *DB) UpdateDataset(d *dataset.Dataset) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, err := db.GetDataset(d.ID); err != nil {
+		return err
+	}
+	if err := db.append(entry{SaveDataset: d}); err != nil {
+		return err
+	}
+	return db.DB.UpdateDataset(d)
+}
+
+// RemoveDataset unregisters a dataset. Variants of the dataset are kept.
+func (db *DB) RemoveDataset(id string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, err := db.GetDataset(id); err != nil {
+		return err
+	}
+	if err := db.append(entry{RemoveDataset: id}); err != nil {
+		return err
+	}
+	return db.DB.RemoveDataset(id)
+}
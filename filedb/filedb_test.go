@@ -0,0 +1,143 @@
+package filedb
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labbcb/brave/search"
+	"github.com/labbcb/brave/variant"
+)
+
+func TestDB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "brave.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vs := []*variant.Variant{
+		{ID: "a", DatasetID: "bipmed", AssemblyID: "hg19", ReferenceName: "1", Start: 100, GeneSymbol: []string{"SCN1A"}, SnpIds: []string{"rs1"}},
+		{ID: "b", DatasetID: "bipmed", AssemblyID: "hg19", ReferenceName: "1", Start: 200},
+		{ID: "c", DatasetID: "bipmed", AssemblyID: "hg38", ReferenceName: "2", Start: 150},
+	}
+	for _, v := range vs {
+		if _, err := db.Save(v, variant.Insert); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Save(vs[0], variant.Insert); err == nil {
+		t.Error("want error when saving duplicate variant")
+	}
+
+	ts := map[*search.Query][]string{
+		{GeneSymbol: "SCN1A"}: {"a"},
+		{SnpID: "rs1"}:        {"a"},
+		{ReferenceName: "1", Start: 150, End: 250}:     {"b"},
+		{ReferenceName: "1", Start: 100}:               {"a"},
+		{AssemblyID: "hg38"}:                           {"c"},
+		{ReferenceName: "3", Start: 1, End: 100000000}: {},
+	}
+	for q, want := range ts {
+		resp, err := db.Search(&search.Input{Queries: []*search.Query{q}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ids(resp.Variants); !equal(got, want) {
+			t.Errorf("query %+v: want %v, got %v", q, want, got)
+		}
+	}
+
+	if err := db.Remove("", "hg19"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	resp, err := db.Search(&search.Input{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(resp.Variants); !equal(got, []string{"c"}) || resp.RecordsTotal != 1 {
+		t.Errorf("after reopening: want [c], got %v (total %d)", got, resp.RecordsTotal)
+	}
+}
+
+func TestOpenTornLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "brave.db")
+	complete := `{"saveDataset":{"id":"bipmed"}}
+{"save":{"id":"a","datasetId":"bipmed","assemblyId":"hg19","referenceName":"1","start":100}}
+`
+	torn := `{"save":{"id":"b","datasetId":"bip`
+	if err := os.WriteFile(path, []byte(complete+torn), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := db.Search(&search.Input{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(resp.Variants); !equal(got, []string{"a"}) {
+		t.Errorf("want [a], got %v", got)
+	}
+	if _, err := db.GetDataset("bipmed"); err != nil {
+		t.Error(err)
+	}
+	db.Close()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), torn) {
+		t.Error("torn line was not removed when compacting")
+	}
+
+	// invalid lines followed by other entries are not torn writes
+	if err := os.WriteFile(path, []byte(torn+"\n"+complete), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("want error at line 1, got %v", err)
+	}
+
+	// dataset entries that can't be replayed are errors too
+	if err := os.WriteFile(path, []byte(`{"removeDataset":"other"}`+"\n"+complete), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path); err == nil {
+		t.Error("want error removing unknown dataset")
+	}
+}
+
+func ids(vs []*variant.Variant) []string {
+	var ids []string
+	for _, v := range vs {
+		ids = append(ids, v.ID)
+	}
+	return ids
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,68 @@
+package mem
+
+import (
+	"sort"
+
+	"github.com/labbcb/brave/dataset"
+)
+
+// SaveDataset registers a dataset. It fails if dataset ID already exists.
+func (db *DB) SaveDataset(d *dataset.Dataset) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, ok := db.datasets[d.ID]; ok {
+		return dataset.ErrExists
+	}
+	db.datasets[d.ID] = d
+	return nil
+}
+
+// UpdateDataset replaces metadata of a registered dataset.
+func (db *DB) UpdateDataset(d *dataset.Dataset) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, ok := db.datasets[d.ID]; !ok {
+		return dataset.ErrNotFound
+	}
+	db.datasets[d.ID] = d
+	return nil
+}
+
+// GetDataset returns a registered dataset.
+func (db *DB) GetDataset(id string) (*dataset.Dataset, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	d, ok := db.datasets[id]
+	if !ok {
+		return nil, dataset.ErrNotFound
+	}
+	return d, nil
+}
+
+// ListDatasets returns all registered datasets sorted by ID.
+func (db *DB) ListDatasets() ([]*dataset.Dataset, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	ds := []*dataset.Dataset{}
+	for _, d := range db.datasets {
+		ds = append(ds, d)
+	}
+	sort.Slice(ds, func(i, j int) bool { return ds[i].ID < ds[j].ID })
+	return ds, nil
+}
+
+// RemoveDataset unregisters a dataset. Variants of the dataset are kept.
+func (db *DB) RemoveDataset(id string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, ok := db.datasets[id]; !ok {
+		return dataset.ErrNotFound
+	}
+	delete(db.datasets, id)
+	return nil
+}
@@ -0,0 +1,57 @@
+package mem
+
+import "sort"
+
+// interval is a genomic interval pointing to a stored variant.
+type interval struct {
+	start, end int32
+	pos        int // position of the variant in DB.variants
+}
+
+// intervalIndex keeps intervals of a single reference sorted by start position.
+// Lookups use binary search so they run in logarithmic time plus the number of hits.
+type intervalIndex struct {
+	intervals []interval
+	maxSpan   int32 // length of the longest interval
+}
+
+// insert adds an interval keeping intervals sorted.
+// Appending in position order, as in sorted VCF files, costs constant time.
+func (idx *intervalIndex) insert(it interval) {
+	if span := it.end - it.start; span > idx.maxSpan {
+		idx.maxSpan = span
+	}
+	n := len(idx.intervals)
+	if n == 0 || idx.intervals[n-1].start <= it.start {
+		idx.intervals = append(idx.intervals, it)
+		return
+	}
+	i := sort.Search(n, func(i int) bool { return idx.intervals[i].start > it.start })
+	idx.intervals = append(idx.intervals, interval{})
+	copy(idx.intervals[i+1:], idx.intervals[i:])
+	idx.intervals[i] = it
+}
+
+// query returns positions of variants which start is between start and end (inclusive).
+func (idx *intervalIndex) query(start, end int32) []int {
+	i := sort.Search(len(idx.intervals), func(i int) bool { return idx.intervals[i].start >= start })
+	var hits []int
+	for ; i < len(idx.intervals) && idx.intervals[i].start <= end; i++ {
+		hits = append(hits, idx.intervals[i].pos)
+	}
+	return hits
+}
+
+// overlaps returns positions of variants which interval overlaps start and end (inclusive).
+// Only intervals starting up to the longest interval length before start are checked.
+func (idx *intervalIndex) overlaps(start, end int32) []int {
+	from := start - idx.maxSpan
+	i := sort.Search(len(idx.intervals), func(i int) bool { return idx.intervals[i].start >= from })
+	var hits []int
+	for ; i < len(idx.intervals) && idx.intervals[i].start <= end; i++ {
+		if idx.intervals[i].end >= start {
+			hits = append(hits, idx.intervals[i].pos)
+		}
+	}
+	return hits
+}
@@ -0,0 +1,253 @@
+package mem
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/labbcb/brave/dataset"
+	"github.com/labbcb/brave/search"
+	"github.com/labbcb/brave/variant"
+)
+
+// DB is an in-memory variant store.
+// Variants are indexed by reference name and position so genomic position and range queries
+// don't need to scan all variants. It is meant for tests, demos and as reference implementation
+// of query semantics.
+type DB struct {
+	mu       sync.RWMutex
+	variants []*variant.Variant
+	ids      map[string]int // position of variant in variants
+	index    map[string]*intervalIndex
+	datasets map[string]*dataset.Dataset
+}
+
+// New creates an empty in-memory store.
+func New() *DB {
+	return &DB{ids: make(map[string]int), index: make(map[string]*intervalIndex), datasets: make(map[string]*dataset.Dataset)}
+}
+
+// Has reports whether there is a variant with the given ID.
+func (db *DB) Has(id string) bool {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	_, ok := db.ids[id]
+	return ok
+}
+
+// Variants returns all variants in storage order.
+func (db *DB) Variants() []*variant.Variant {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return append([]*variant.Variant(nil), db.variants...)
+}
+
+// Save stores variant in memory according to write mode.
+func (db *DB) Save(v *variant.Variant, mode variant.WriteMode) (*variant.WriteResult, error) {
+	return db.SaveMany([]*variant.Variant{v}, mode)
+}
+
+// SaveMany stores a batch of variants in memory according to write mode.
+// In insert mode variants that already exist, or are repeated in batch, are not stored
+// and reported as variant.ErrDuplicate along with counts of the other variants, like MongoDB unordered inserts.
+func (db *DB) SaveMany(vs []*variant.Variant, mode variant.WriteMode) (*variant.WriteResult, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	result := new(variant.WriteResult)
+	var duplicates []string
+	for _, v := range vs {
+		pos, ok := db.ids[v.ID]
+		switch {
+		case !ok:
+			db.save(v)
+			result.Created++
+		case mode == variant.Insert:
+			duplicates = append(duplicates, v.ID)
+		case mode == variant.Replace:
+			db.replace(pos, v)
+			result.Replaced++
+		default:
+			result.Skipped++
+		}
+	}
+	if len(duplicates) > 0 {
+		return result, variant.DuplicateError(len(duplicates), duplicates[0])
+	}
+	return result, nil
+}
+
+func (db *DB) save(v *variant.Variant) {
+	db.ids[v.ID] = len(db.variants)
+	db.variants = append(db.variants, v)
+
+	idx, ok := db.index[v.ReferenceName]
+	if !ok {
+		idx = new(intervalIndex)
+		db.index[v.ReferenceName] = idx
+	}
+	idx.insert(interval{start: v.Start, end: v.Stop(), pos: len(db.variants) - 1})
+}
+
+// replace overwrites variant at position keeping storage order.
+// Index is rebuilt only if variant location changed.
+func (db *DB) replace(pos int, v *variant.Variant) {
+	old := db.variants[pos]
+	db.variants[pos] = v
+	if old.ReferenceName != v.ReferenceName || old.Start != v.Start || old.Stop() != v.Stop() {
+		db.rebuild(db.variants)
+	}
+}
+
+// rebuild resets store with the given variants.
+func (db *DB) rebuild(variants []*variant.Variant) {
+	db.variants = nil
+	db.ids = make(map[string]int)
+	db.index = make(map[string]*intervalIndex)
+	for _, v := range variants {
+		db.save(v)
+	}
+}
+
+// Search returns variants that match at least one query and the query expression,
+// in storage order or ordered by input sort fields.
+// Results in position order are paged by cursor if it is given, like MongoDB store filtered variants are not counted then.
+func (db *DB) Search(i *search.Input) (*search.Response, error) {
+	expr, err := i.Expr()
+	if err != nil {
+		return nil, err
+	}
+	fields, after, err := i.Paging()
+	if err != nil {
+		return nil, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var matched []*variant.Variant
+	for _, pos := range db.candidates(i) {
+		if v := db.variants[pos]; expr.Match(v) {
+			matched = append(matched, v)
+		}
+	}
+	if len(fields) > 0 {
+		sort.SliceStable(matched, func(a, b int) bool { return search.Less(matched[a], matched[b], fields) })
+	}
+
+	rest := matched
+	start := max(i.Start, 0)
+	if after != nil {
+		n := sort.Search(len(matched), func(n int) bool { return after.Before(matched[n]) })
+		rest, start = matched[n:], 0
+	}
+
+	variants := []*variant.Variant{}
+	if start < int64(len(rest)) {
+		end := int64(len(rest))
+		if i.Length > 0 && start+i.Length < end {
+			end = start + i.Length
+		}
+		variants = append(variants, rest[start:end]...)
+	}
+
+	response := &search.Response{Draw: i.Draw, Variants: variants, RecordsTotal: int64(len(db.variants))}
+	if after == nil {
+		response.RecordsFiltered = int64(len(matched))
+	}
+	if search.IsPositionOrder(fields) && i.Length > 0 && start+i.Length < int64(len(rest)) {
+		response.NextCursor = search.NewCursor(variants[len(variants)-1]).Encode()
+	}
+	return response, nil
+}
+
+// Export calls fn for every variant that matches input, in position order.
+// Matching variants are selected first, so fn may change the database.
+func (db *DB) Export(i *search.Input, fn func(v *variant.Variant) error) error {
+	resp, err := db.Search(&search.Input{Queries: i.Queries, Q: i.Q, Sort: search.SortPosition})
+	if err != nil {
+		return err
+	}
+	for _, v := range resp.Variants {
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CountAlleles counts alternate alleles of variants that match input.
+func (db *DB) CountAlleles(i *search.Input) (int64, error) {
+	expr, err := i.Expr()
+	if err != nil {
+		return 0, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var n int64
+	for _, pos := range db.candidates(i) {
+		if v := db.variants[pos]; expr.Match(v) {
+			n += int64(len(v.AlternateBases))
+		}
+	}
+	return n, nil
+}
+
+// candidates returns sorted positions of variants that may match input.
+// If any query is not restricted to a genomic position or range then all variants are candidates.
+func (db *DB) candidates(i *search.Input) []int {
+	var set map[int]bool
+	if len(i.Queries) > 0 {
+		set = make(map[int]bool)
+	}
+	for _, q := range i.Queries {
+		if q.ReferenceName == "" || q.Start == 0 {
+			set = nil
+			break
+		}
+		idx, ok := db.index[q.ReferenceName]
+		if !ok {
+			continue
+		}
+		hits := idx.query(q.Start, q.Start)
+		if q.End != 0 {
+			hits = idx.overlaps(q.Start, q.End)
+		}
+		for _, pos := range hits {
+			set[pos] = true
+		}
+	}
+
+	if set == nil {
+		all := make([]int, len(db.variants))
+		for pos := range all {
+			all[pos] = pos
+		}
+		return all
+	}
+
+	ps := make([]int, 0, len(set))
+	for pos := range set {
+		ps = append(ps, pos)
+	}
+	sort.Ints(ps)
+	return ps
+}
+
+// Remove removes variants given a dataset ID and/or assembly ID.
+// If both are zero value them it deletes all variants.
+func (db *DB) Remove(datasetID string, assemblyID string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var kept []*variant.Variant
+	for _, v := range db.variants {
+		if (datasetID == "" || v.DatasetID == datasetID) && (assemblyID == "" || v.AssemblyID == assemblyID) {
+			continue
+		}
+		kept = append(kept, v)
+	}
+	db.rebuild(kept)
+	return nil
+}
@@ -0,0 +1,223 @@
+package mem
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/labbcb/brave/search"
+	"github.com/labbcb/brave/variant"
+)
+
+func TestSearch(t *testing.T) {
+	db := New()
+	alts := [][]string{{"G"}, {"C"}, {"T"}, {"G", "T"}}
+	for i, pos := range []int32{300, 100, 200, 100} {
+		v := &variant.Variant{ID: fmt.Sprint(i), DatasetID: "bipmed", AssemblyID: "hg19", ReferenceName: "1", Start: pos, ReferenceBases: "A", AlternateBases: alts[i]}
+		if _, err := db.Save(v, variant.Insert); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Save(&variant.Variant{ID: "4", DatasetID: "bipmed", AssemblyID: "hg38", ReferenceName: "2", Start: 100, GeneSymbol: []string{"SCN1A"}}, variant.Insert); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := []struct {
+		input *search.Input
+		want  string
+		total int64
+	}{
+		{&search.Input{}, "[0 1 2 3 4]", 5},
+		{&search.Input{Queries: []*search.Query{{ReferenceName: "1", Start: 100}}}, "[1 3]", 2},
+		{&search.Input{Queries: []*search.Query{{ReferenceName: "1", Start: 150, End: 300}}}, "[0 2]", 2},
+		{&search.Input{Queries: []*search.Query{search.Parse("1:100:A>C")}}, "[1]", 1},
+		{&search.Input{Queries: []*search.Query{search.Parse("1-100-A-T")}}, "[3]", 1},
+		{&search.Input{Queries: []*search.Query{search.Parse("1:100:G>A")}}, "[]", 0},
+		{&search.Input{Queries: []*search.Query{{ReferenceName: "1", Start: 150, End: 300}, {GeneSymbol: "SCN1A"}}}, "[0 2 4]", 3},
+		{&search.Input{Queries: []*search.Query{{ReferenceName: "2", Start: 100, AssemblyID: "hg19"}}}, "[]", 0},
+		{&search.Input{Start: 1, Length: 2}, "[1 2]", 5},
+		{&search.Input{Sort: "-position"}, "[4 0 2 1 3]", 5},
+		{&search.Input{Sort: "position", Start: 1, Length: 2}, "[3 2]", 5},
+		{&search.Input{Start: 10}, "[]", 5},
+	}
+	for _, tc := range ts {
+		resp, err := db.Search(tc.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, v := range resp.Variants {
+			ids = append(ids, v.ID)
+		}
+		if got := fmt.Sprint(ids); got != tc.want || resp.RecordsFiltered != tc.total {
+			t.Errorf("want %s (%d filtered), got %s (%d filtered)", tc.want, tc.total, got, resp.RecordsFiltered)
+		}
+	}
+
+	if err := db.Remove("", "hg19"); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := db.Search(&search.Input{Queries: []*search.Query{{ReferenceName: "2", Start: 100}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.RecordsTotal != 1 || len(resp.Variants) != 1 {
+		t.Errorf("want one variant after removal, got %d", resp.RecordsTotal)
+	}
+}
+
+func TestSaveMany(t *testing.T) {
+	db := New()
+	vs := []*variant.Variant{{ID: "a", ReferenceName: "1", Start: 100}, {ID: "b", ReferenceName: "1", Start: 200}}
+	if _, err := db.SaveMany(vs, variant.Insert); err != nil {
+		t.Fatal(err)
+	}
+
+	again := []*variant.Variant{{ID: "b", ReferenceName: "1", Start: 200, SampleCount: 1}, {ID: "c", ReferenceName: "1", Start: 300}}
+	// like MongoDB unordered inserts, variants that don't exist are stored
+	result, err := db.SaveMany(again, variant.Insert)
+	if !errors.Is(err, variant.ErrDuplicate) {
+		t.Errorf("want duplicate error when inserting existing variant, got %v", err)
+	}
+	if result == nil || result.Created != 1 || len(db.Variants()) != 3 {
+		t.Errorf("want one variant created after duplicate insert, got %+v and %d variants", result, len(db.Variants()))
+	}
+
+	ts := []struct {
+		mode  variant.WriteMode
+		want  variant.WriteResult
+		count int
+	}{
+		{variant.Skip, variant.WriteResult{Skipped: 2}, 0},
+		{variant.Replace, variant.WriteResult{Replaced: 2}, 1},
+	}
+	for _, tc := range ts {
+		got, err := db.SaveMany(again, tc.mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *got != tc.want {
+			t.Errorf("%s: want %+v, got %+v", tc.mode, tc.want, *got)
+		}
+		if n := db.Variants()[1].SampleCount; n != tc.count {
+			t.Errorf("%s: want sample count %d, got %d", tc.mode, tc.count, n)
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	db := New()
+	vs := []*variant.Variant{
+		{ID: "a", DatasetID: "bipmed", AssemblyID: "hg19", TotalSamples: 10, ReferenceName: "1", Start: 100, CLNSIG: "Pathogenic", Type: []string{"missense_variant", "missense_variant"}},
+		{ID: "b", DatasetID: "bipmed", AssemblyID: "hg19", TotalSamples: 10, ReferenceName: "2", Start: 200, Type: []string{"synonymous_variant"}},
+		{ID: "c", DatasetID: "bipmed", AssemblyID: "hg38", TotalSamples: 10, ReferenceName: "1", Start: 300},
+	}
+	if _, err := db.SaveMany(vs, variant.Insert); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := db.Stats("bipmed", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("want stats of 2 assemblies, got %d", len(stats))
+	}
+
+	s := stats[0]
+	if s.AssemblyID != "hg19" || s.Variants != 2 || s.TotalSamples != 10 || s.ClinVar != 1 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+	if got := fmt.Sprint(s.Chromosomes, s.Types); got != "map[1:1 2:1] map[missense_variant:1 synonymous_variant:1]" {
+		t.Errorf("unexpected counts %s", got)
+	}
+}
+
+func TestSearchOverlap(t *testing.T) {
+	db := New()
+	for i, v := range []*variant.Variant{
+		{ReferenceName: "1", Start: 90, ReferenceBases: "ACGTACGTACGTACG"},
+		{ReferenceName: "1", Start: 110, ReferenceBases: "A"},
+		{ReferenceName: "1", Start: 150, End: 1000, ReferenceBases: "N"},
+		{ReferenceName: "1", Start: 2000, ReferenceBases: "A"},
+	} {
+		v.ID = fmt.Sprint(i)
+		if _, err := db.Save(v, variant.Insert); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ts := []struct {
+		query *search.Query
+		want  string
+	}{
+		{&search.Query{ReferenceName: "1", Start: 100, End: 200}, "[0 1 2]"},
+		{&search.Query{ReferenceName: "1", Start: 100, End: 200, Contained: true}, "[1]"},
+		{&search.Query{ReferenceName: "1", Start: 105, End: 109}, "[]"},
+		{&search.Query{ReferenceName: "1", Start: 500, End: 600}, "[2]"},
+		{&search.Query{ReferenceName: "1", Start: 90}, "[0]"},
+	}
+	for _, tc := range ts {
+		resp, err := db.Search(&search.Input{Queries: []*search.Query{tc.query}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, v := range resp.Variants {
+			ids = append(ids, v.ID)
+		}
+		if got := fmt.Sprint(ids); got != tc.want {
+			t.Errorf("%+v: want %s, got %s", tc.query, tc.want, got)
+		}
+	}
+}
+
+func TestSearchCursor(t *testing.T) {
+	db := New()
+	for i, chrom := range []string{"X", "2", "10", "2", "1", "GL000192.1", "MT"} {
+		v := &variant.Variant{ID: fmt.Sprint(i), ReferenceName: chrom, Start: 100}
+		if _, err := db.Save(v, variant.Insert); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var pages []string
+	input := &search.Input{Sort: search.SortPosition, Length: 3}
+	for {
+		resp, err := db.Search(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, v := range resp.Variants {
+			ids = append(ids, v.ID)
+		}
+		pages = append(pages, fmt.Sprint(ids))
+		if len(pages) == 1 && resp.RecordsFiltered != 7 {
+			t.Errorf("want 7 filtered, got %d", resp.RecordsFiltered)
+		}
+		if len(pages) > 1 && resp.RecordsFiltered != 0 {
+			t.Errorf("want pages after cursor not counting filtered variants, got %d", resp.RecordsFiltered)
+		}
+		if resp.NextCursor == "" {
+			break
+		}
+		input.Cursor = resp.NextCursor
+		// variants before the cursor do not shift next pages
+		if len(pages) == 1 {
+			if _, err := db.Save(&variant.Variant{ID: "7", ReferenceName: "1", Start: 50}, variant.Insert); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if got, want := fmt.Sprint(pages), "[[4 1 3] [2 0 6] [5]]"; got != want {
+		t.Errorf("want pages %s, got %s", want, got)
+	}
+
+	cursor := search.NewCursor(&variant.Variant{ID: "0", ReferenceName: "X", Start: 100}).Encode()
+	for _, input := range []*search.Input{{Cursor: "x"}, {Cursor: cursor, Sort: "-position"}} {
+		if _, err := db.Search(input); !errors.Is(err, search.ErrInvalidCursor) {
+			t.Errorf("%+v: want ErrInvalidCursor, got %v", input, err)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package mem
+
+import (
+	"sort"
+
+	"github.com/labbcb/brave/dataset"
+)
+
+// Stats summarizes variants per dataset and assembly, optionally restricted to a dataset and/or assembly.
+func (db *DB) Stats(datasetID, assemblyID string) ([]*dataset.Stats, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	type key struct{ dataset, assembly string }
+	stats := make(map[key]*dataset.Stats)
+	for _, v := range db.variants {
+		if (datasetID != "" && v.DatasetID != datasetID) || (assemblyID != "" && v.AssemblyID != assemblyID) {
+			continue
+		}
+
+		k := key{v.DatasetID, v.AssemblyID}
+		s, ok := stats[k]
+		if !ok {
+			s = &dataset.Stats{DatasetID: v.DatasetID, AssemblyID: v.AssemblyID, Chromosomes: make(map[string]int64), Types: make(map[string]int64)}
+			stats[k] = s
+		}
+
+		s.Variants++
+		s.Chromosomes[v.ReferenceName]++
+		if v.CLNSIG != "" {
+			s.ClinVar++
+		}
+		if v.TotalSamples > s.TotalSamples {
+			s.TotalSamples = v.TotalSamples
+		}
+		seen := make(map[string]bool)
+		for _, t := range v.Type {
+			if !seen[t] {
+				seen[t] = true
+				s.Types[t]++
+			}
+		}
+	}
+
+	ss := []*dataset.Stats{}
+	for _, s := range stats {
+		ss = append(ss, s)
+	}
+	sort.Slice(ss, func(i, j int) bool {
+		if ss[i].DatasetID != ss[j].DatasetID {
+			return ss[i].DatasetID < ss[j].DatasetID
+		}
+		return ss[i].AssemblyID < ss[j].AssemblyID
+	})
+	return ss, nil
+}
@@ -0,0 +1,69 @@
+package mongo
+
+import (
+	"github.com/labbcb/brave/dataset"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SaveDataset registers a dataset. It fails if dataset ID already exists.
+func (db *DB) SaveDataset(d *dataset.Dataset) error {
+	_, err := db.client.Database(db.database).Collection("datasets").InsertOne(nil, d)
+	if mongo.IsDuplicateKeyError(err) {
+		return dataset.ErrExists
+	}
+	return err
+}
+
+// UpdateDataset replaces metadata of a registered dataset.
+func (db *DB) UpdateDataset(d *dataset.Dataset) error {
+	res, err := db.client.Database(db.database).Collection("datasets").ReplaceOne(nil, bson.D{{"_id", d.ID}}, d)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return dataset.ErrNotFound
+	}
+	return nil
+}
+
+// GetDataset returns a registered dataset.
+func (db *DB) GetDataset(id string) (*dataset.Dataset, error) {
+	var d dataset.Dataset
+	err := db.client.Database(db.database).Collection("datasets").FindOne(nil, bson.D{{"_id", id}}).Decode(&d)
+	if err == mongo.ErrNoDocuments {
+		return nil, dataset.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ListDatasets returns all registered datasets sorted by ID.
+func (db *DB) ListDatasets() ([]*dataset.Dataset, error) {
+	cur, err := db.client.Database(db.database).Collection("datasets").
+		Find(nil, bson.D{}, options.Find().SetSort(bson.D{{"_id", 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	ds := []*dataset.Dataset{}
+	if err := cur.All(nil, &ds); err != nil {
+		return nil, err
+	}
+	return ds, nil
+}
+
+// RemoveDataset unregisters a dataset. Variants of the dataset are kept.
+func (db *DB) RemoveDataset(id string) error {
+	res, err := db.client.Database(db.database).Collection("datasets").DeleteOne(nil, bson.D{{"_id", id}})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return dataset.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package mongo
+
+import (
+	"github.com/labbcb/brave/search"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// comparisonOperators maps comparison operators of query expressions to MongoDB operators.
+var comparisonOperators = map[string]string{"<": "$lt", "<=": "$lte", ">": "$gt", ">=": "$gte", "=": "$eq"}
+
+// exprFilter translates a query expression to a MongoDB filter.
+// Maximum spans by reference name bound start positions of range queries (see queryFilter).
+func exprFilter(e search.Expr, spans map[string]int32) bson.D {
+	switch x := e.(type) {
+	case search.And:
+		if len(x) == 0 {
+			return bson.D{}
+		}
+		return bson.D{{"$and", exprFilters(x, spans)}}
+	case search.Or:
+		if len(x) == 0 {
+			return bson.D{{"_id", bson.D{{"$exists", false}}}}
+		}
+		return bson.D{{"$or", exprFilters(x, spans)}}
+	case *search.Not:
+		return bson.D{{"$nor", bson.A{exprFilter(x.Expr, spans)}}}
+	case *search.Term:
+		return queryFilter(x.Query, spans)
+	case *search.Comparison:
+		cmp := bson.D{{comparisonOperators[x.Op], x.Value}}
+		switch x.Field {
+		case search.AlleleFrequencyField:
+			// float32 frequencies are stored as doubles with float32 precision
+			cmp = bson.D{{comparisonOperators[x.Op], x.AlleleFrequencyValue()}}
+			return bson.D{{alleleFrequencyField(x.Population), bson.D{{"$elemMatch", cmp}}}}
+		case search.CoverageField:
+			return bson.D{{"coverage.median", cmp}}
+		case search.GenotypeQualityField:
+			return bson.D{{"genotypeQuality.median", cmp}}
+		}
+	}
+	panic("mongo: unsupported query expression")
+}
+
+func exprFilters(es []search.Expr, spans map[string]int32) bson.A {
+	fs := make(bson.A, len(es))
+	for i, e := range es {
+		fs[i] = exprFilter(e, spans)
+	}
+	return fs
+}
+
+// queryFilter translates a query to a MongoDB filter.
+// A query without conditions matches all variants.
+// Variants overlapping a range start at most the maximum span of its reference sequence before it,
+// so the start position index is scanned only from there. Without a known span the scan begins at the sequence start.
+func queryFilter(q *search.Query, spans map[string]int32) bson.D {
+	var fq bson.A
+	if q.AssemblyID != "" {
+		fq = append(fq, bson.D{{"assemblyId", q.AssemblyID}})
+	}
+	if q.GeneSymbol != "" {
+		fq = append(fq, bson.D{{"geneSymbol", bson.D{{"$all", bson.A{q.GeneSymbol}}}}})
+	}
+	if q.DatasetID != "" {
+		fq = append(fq, bson.D{{"datasetId", q.DatasetID}})
+	}
+	if q.SnpID != "" {
+		fq = append(fq, bson.D{{"snpIds", bson.D{{"$all", bson.A{q.SnpID}}}}})
+	}
+	if q.ReferenceBases != "" {
+		fq = append(fq, bson.D{{"referenceBases", q.ReferenceBases}})
+	}
+	if q.AlternateBases != "" {
+		// multi-allelic variants match if any of their alternate alleles is equal
+		fq = append(fq, bson.D{{"alternateBases", bson.D{{"$all", bson.A{q.AlternateBases}}}}})
+	}
+	if (q.MinAlleleFrequency != 0 || q.MaxAlleleFrequency != 0) && q.AlternateBases != "" {
+		fq = append(fq, alleleFrequencyExpr(q))
+	} else if q.MinAlleleFrequency != 0 || q.MaxAlleleFrequency != 0 {
+		// the same alternate allele must satisfy both limits
+		af := bson.D{{"$gte", q.MinAlleleFrequency}}
+		if q.MaxAlleleFrequency != 0 {
+			af = append(af, bson.E{"$lte", q.MaxAlleleFrequency})
+		}
+		fq = append(fq, bson.D{{alleleFrequencyField(q.Population), bson.D{{"$elemMatch", af}}}})
+	}
+	if len(q.Types) > 0 {
+		fq = append(fq, bson.D{{"type", bson.D{{"$in", q.Types}}}})
+	}
+	if len(q.ClinicalSignificance) > 0 {
+		var terms bson.A
+		for _, term := range q.ClinicalSignificance {
+			terms = append(terms, primitive.Regex{Pattern: search.ClinicalSignificancePattern(term), Options: "i"})
+		}
+		fq = append(fq, bson.D{{"clnsig", bson.D{{"$in", terms}}}})
+	}
+	if q.MinCoverage != 0 {
+		fq = append(fq, bson.D{{"coverage.median", bson.D{{"$gte", q.MinCoverage}}}})
+	}
+	if q.MinGenotypeQuality != 0 {
+		fq = append(fq, bson.D{{"genotypeQuality.median", bson.D{{"$gte", q.MinGenotypeQuality}}}})
+	}
+	if q.ReferenceName != "" && q.Start != 0 && q.End != 0 && q.Contained {
+		fq = append(fq, bson.D{{"$and", bson.A{
+			bson.D{{"referenceName", q.ReferenceName}},
+			bson.D{{"start", bson.D{{"$gte", q.Start}}}},
+			stopFilter("$lte", q.End),
+		}}})
+	} else if q.ReferenceName != "" && q.Start != 0 && q.End != 0 {
+		start := bson.D{{"$lte", q.End}}
+		if n, ok := spans[q.ReferenceName]; ok {
+			start = append(start, bson.E{"$gte", q.Start - n})
+		}
+		fq = append(fq, bson.D{{"$and", bson.A{
+			bson.D{{"referenceName", q.ReferenceName}},
+			bson.D{{"start", start}},
+			stopFilter("$gte", q.Start),
+		}}})
+	} else if q.ReferenceName != "" && q.Start != 0 {
+		fq = append(fq, bson.D{{"$and", bson.A{
+			bson.D{{"referenceName", q.ReferenceName}},
+			bson.D{{"start", q.Start}},
+		}}})
+	}
+	if len(fq) == 0 {
+		return bson.D{}
+	}
+	return bson.D{{"$and", fq}}
+}
+
+// alleleFrequencyField returns the field of allele frequencies of a population, or of all samples if population is empty.
+func alleleFrequencyField(population string) string {
+	if population == "" {
+		return "alleleFrequency"
+	}
+	return "populations." + population + ".alleleFrequency"
+}
+
+// alleleFrequencyExpr compares the allele frequency of the query alternate allele with frequency limits.
+// Missing frequencies are null, which is less than any number, so they never satisfy the minimum.
+func alleleFrequencyExpr(q *search.Query) bson.D {
+	index := bson.D{{"$indexOfArray", bson.A{"$alternateBases", q.AlternateBases}}}
+	af := bson.D{{"$arrayElemAt", bson.A{"$" + alleleFrequencyField(q.Population), index}}}
+	cmp := bson.A{
+		bson.D{{"$gte", bson.A{index, 0}}},
+		bson.D{{"$gte", bson.A{af, q.MinAlleleFrequency}}},
+	}
+	if q.MaxAlleleFrequency != 0 {
+		cmp = append(cmp, bson.D{{"$lte", bson.A{af, q.MaxAlleleFrequency}}})
+	}
+	return bson.D{{"$expr", bson.D{{"$and", cmp}}}}
+}
+
+// stopFilter compares the last position of variants with pos.
+// Variants saved before end positions were stored fall back to start position.
+func stopFilter(op string, pos int32) bson.D {
+	return bson.D{{"$or", bson.A{
+		bson.D{{"end", bson.D{{op, pos}}}},
+		bson.D{{"end", bson.D{{"$exists", false}}}, {"start", bson.D{{op, pos}}}},
+	}}}
+}
+
+// chromosomeOrderExpr computes natural chromosome order of variants like search.ChromosomeOrder,
+// for variants saved before it was stored.
+func chromosomeOrderExpr() bson.D {
+	index := bson.D{{"$indexOfArray", bson.A{search.Chromosomes, "$referenceName"}}}
+	return bson.D{{"$cond", bson.A{bson.D{{"$lt", bson.A{index, 0}}}, len(search.Chromosomes), index}}}
+}
+
+// sortPipeline filters, sorts and pages variants. Ties are broken by _id.
+// Position order uses stored natural chromosome order (see search.ChromosomeOrder), unknown names come after them.
+func sortPipeline(filter bson.D, fields []search.SortField, skip, limit int64) mongo.Pipeline {
+	var sort bson.D
+	for _, f := range fields {
+		dir := 1
+		if f.Desc {
+			dir = -1
+		}
+		switch f.Key {
+		case search.SortPosition:
+			sort = append(sort, bson.E{"chromosomeOrder", dir}, bson.E{"referenceName", dir}, bson.E{"start", dir})
+		case search.SortAlleleFrequency:
+			sort = append(sort, bson.E{"alleleFrequency", dir})
+		case search.SortGene:
+			sort = append(sort, bson.E{"geneSymbol", dir})
+		case search.SortSampleCount:
+			sort = append(sort, bson.E{"sampleCount", dir})
+		case search.SortCoverage:
+			sort = append(sort, bson.E{"coverage.median", dir})
+		}
+	}
+	sort = append(sort, bson.E{"_id", 1})
+
+	pipeline := mongo.Pipeline{{{"$match", filter}}, {{"$sort", sort}}}
+	if skip > 0 {
+		pipeline = append(pipeline, bson.D{{"$skip", skip}})
+	}
+	if limit > 0 {
+		pipeline = append(pipeline, bson.D{{"$limit", limit}})
+	}
+	return pipeline
+}
+
+// cursorFilter matches variants after the cursor in position order.
+// It compares stored chromosome orders, reference names and start positions, so the position sort index is used.
+func cursorFilter(c *search.Cursor) bson.D {
+	order := search.ChromosomeOrder(c.ReferenceName)
+	return bson.D{{"$or", bson.A{
+		bson.D{
+			{"chromosomeOrder", order},
+			{"referenceName", c.ReferenceName},
+			{"$or", bson.A{
+				bson.D{{"start", bson.D{{"$gt", c.Start}}}},
+				bson.D{{"start", c.Start}, {"_id", bson.D{{"$gt", c.ID}}}},
+			}},
+		},
+		bson.D{{"chromosomeOrder", order}, {"referenceName", bson.D{{"$gt", c.ReferenceName}}}},
+		bson.D{{"chromosomeOrder", bson.D{{"$gt", order}}}},
+	}}}
+}
@@ -1,6 +1,8 @@
 package mongo
 
 import (
+	"errors"
+
 	"github.com/labbcb/brave/search"
 	"github.com/labbcb/brave/variant"
 	"go.mongodb.org/mongo-driver/bson"
@@ -23,54 +25,184 @@ func Connect(uri string, database string) (*DB, error) {
 	return &DB{client: c, database: database}, nil
 }
 
-// Save stores variant to Mongo database
-func (db *DB) Save(v *variant.Variant) error {
-	_, err := db.client.Database(db.database).Collection("variants").InsertOne(nil, v)
-	if err != nil {
-		return err
-	}
-	return nil
+// Save stores variant to Mongo database according to write mode.
+func (db *DB) Save(v *variant.Variant, mode variant.WriteMode) (*variant.WriteResult, error) {
+	return db.SaveMany([]*variant.Variant{v}, mode)
 }
 
-// Search is the main method to search for variants.
-func (db *DB) Search(i *search.Input) (*search.Response, error) {
-	var filters bson.A
-	for _, q := range i.Queries {
-		var fq bson.A
-		if q.AssemblyID != "" {
-			fq = append(fq, bson.D{{"assemblyId", q.AssemblyID}})
+// SaveMany stores a batch of variants to Mongo database with a single request.
+// Insert mode fails on existing variants, replace mode upserts whole documents
+// and skip mode only inserts variants that don't exist.
+// Writes are unordered, all variants without errors are stored.
+// Existing variants in insert mode are reported as variant.ErrDuplicate along with counts of the other variants.
+func (db *DB) SaveMany(vs []*variant.Variant, mode variant.WriteMode) (*variant.WriteResult, error) {
+	if err := db.saveSpans(vs); err != nil {
+		return nil, err
+	}
+	collection := db.client.Database(db.database).Collection("variants")
+
+	if mode == variant.Insert {
+		docs := make([]interface{}, len(vs))
+		for i, v := range vs {
+			docs[i] = newDocument(v)
 		}
-		if q.GeneSymbol != "" {
-			fq = append(fq, bson.D{{"geneSymbol", bson.D{{"$all", bson.A{q.GeneSymbol}}}}})
+		_, err := collection.InsertMany(nil, docs, options.InsertMany().SetOrdered(false))
+		if err != nil {
+			return duplicates(vs, err)
 		}
-		if q.DatasetID != "" {
-			fq = append(fq, bson.D{{"datasetId", q.DatasetID}})
+		return &variant.WriteResult{Created: int64(len(vs))}, nil
+	}
+
+	models := make([]mongo.WriteModel, len(vs))
+	for i, v := range vs {
+		filter := bson.D{{"_id", v.ID}}
+		if mode == variant.Replace {
+			models[i] = mongo.NewReplaceOneModel().SetFilter(filter).SetReplacement(newDocument(v)).SetUpsert(true)
+			continue
 		}
-		if q.SnpID != "" {
-			fq = append(fq, bson.D{{"snpIds", bson.D{{"$all", bson.A{q.SnpID}}}}})
+		doc, err := withoutID(newDocument(v))
+		if err != nil {
+			return nil, err
 		}
-		if q.ReferenceName != "" && q.Start != 0 && q.End != 0 {
-			fq = append(fq, bson.D{{"$and", bson.A{
-				bson.D{{"referenceName", q.ReferenceName}},
-				bson.D{{"start", bson.D{{"$gte", q.Start}}}},
-				bson.D{{"start", bson.D{{"$lte", q.End}}}},
-			}}})
-		} else if q.ReferenceName != "" && q.Start != 0 {
-			fq = append(fq, bson.D{{"$and", bson.A{
-				bson.D{{"referenceName", q.ReferenceName}},
-				bson.D{{"start", q.Start}},
-			}}})
+		models[i] = mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(bson.D{{"$setOnInsert", doc}}).SetUpsert(true)
+	}
+
+	res, err := collection.BulkWrite(nil, models, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &variant.WriteResult{Created: res.UpsertedCount}
+	if mode == variant.Replace {
+		result.Replaced = res.MatchedCount
+	} else {
+		result.Skipped = res.MatchedCount
+	}
+	return result, nil
+}
+
+// duplicates converts an unordered insert error whose write errors are all duplicate keys
+// to variant.ErrDuplicate, counting the other variants as created. Other errors are returned as is.
+func duplicates(vs []*variant.Variant, err error) (*variant.WriteResult, error) {
+	var bwe mongo.BulkWriteException
+	if !errors.As(err, &bwe) || bwe.WriteConcernError != nil || len(bwe.WriteErrors) == 0 {
+		return nil, err
+	}
+	for _, we := range bwe.WriteErrors {
+		if !mongo.IsDuplicateKeyError(we) {
+			return nil, err
 		}
-		filters = append(filters, bson.D{{"$and", fq}})
 	}
+	result := &variant.WriteResult{Created: int64(len(vs) - len(bwe.WriteErrors))}
+	return result, variant.DuplicateError(len(bwe.WriteErrors), vs[bwe.WriteErrors[0].Index].ID)
+}
 
-	filter := bson.D{}
-	if len(filters) > 0 {
-		filter = bson.D{{"$or", filters}}
+// document is a stored variant. Natural chromosome order is stored too, so sorting by position uses an index.
+type document struct {
+	*variant.Variant `bson:",inline"`
+	ChromosomeOrder  int `bson:"chromosomeOrder"`
+}
+
+func newDocument(v *variant.Variant) *document {
+	return &document{Variant: v, ChromosomeOrder: search.ChromosomeOrder(v.ReferenceName)}
+}
+
+// withoutID converts variant document to document without _id field, which can't be set by update operators.
+func withoutID(d *document) (bson.M, error) {
+	b, err := bson.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(b, &doc); err != nil {
+		return nil, err
 	}
+	delete(doc, "_id")
+	return doc, nil
+}
 
-	cur, err := db.client.Database(db.database).Collection("variants").
-		Find(nil, filter, &options.FindOptions{Limit: &i.Length, Skip: &i.Start})
+// Indexes are the indexes that support filters and sorts built by Search, Export and Remove
+// (queryFilter, exprFilter, cursorFilter and sortPipeline).
+// Sort keys are indexed in both directions followed by _id, which breaks ties in ascending order.
+// Update them whenever a query filters or sorts by another field.
+// Population allele frequencies are not indexed, their fields depend on imported groups of samples.
+var Indexes = []mongo.IndexModel{
+	{Keys: bson.D{{"geneSymbol", 1}, {"_id", 1}}},                                             // gene queries and sorting
+	{Keys: bson.D{{"geneSymbol", -1}, {"_id", 1}}},                                            // descending sorting by gene
+	{Keys: bson.D{{"snpIds", 1}}},                                                             // dbSNP ID queries
+	{Keys: bson.D{{"referenceName", 1}, {"start", 1}}},                                        // positions and ranges
+	{Keys: bson.D{{"chromosomeOrder", 1}, {"referenceName", 1}, {"start", 1}, {"_id", 1}}},    // sorting by position and cursors
+	{Keys: bson.D{{"chromosomeOrder", -1}, {"referenceName", -1}, {"start", -1}, {"_id", 1}}}, // descending sorting by position
+	{Keys: bson.D{{"referenceName", 1}, {"end", 1}}},                                          // range ends (stopFilter)
+	{Keys: bson.D{{"datasetId", 1}, {"assemblyId", 1}}},                                       // dataset queries, Remove and Stats
+	{Keys: bson.D{{"assemblyId", 1}}},                                                         // assembly queries
+	{Keys: bson.D{{"alleleFrequency", 1}, {"_id", 1}}},                                        // allele frequency limits, comparisons and sorting
+	{Keys: bson.D{{"alleleFrequency", -1}, {"_id", 1}}},                                       // descending sorting by allele frequency
+	{Keys: bson.D{{"type", 1}}},                                                               // variant type queries
+	{Keys: bson.D{{"clnsig", 1}}},                                                             // clinical significance queries
+	{Keys: bson.D{{"coverage.median", 1}, {"_id", 1}}},                                        // coverage limits, comparisons and sorting
+	{Keys: bson.D{{"coverage.median", -1}, {"_id", 1}}},                                       // descending sorting by coverage
+	{Keys: bson.D{{"sampleCount", 1}, {"_id", 1}}},                                            // sorting by sample count
+	{Keys: bson.D{{"sampleCount", -1}, {"_id", 1}}},                                           // descending sorting by sample count
+}
+
+// EnsureIndexes creates missing indexes of variants collection and returns their names.
+// Existing indexes with the same keys are left untouched.
+// Maximum spans and chromosome orders of variants saved by older versions are computed too,
+// so range queries can be bounded and position sorts use indexes.
+func (db *DB) EnsureIndexes() ([]string, error) {
+	if err := db.computeSpans(); err != nil {
+		return nil, err
+	}
+	_, err := db.client.Database(db.database).Collection("variants").UpdateMany(nil,
+		bson.D{{"chromosomeOrder", bson.D{{"$exists", false}}}},
+		mongo.Pipeline{{{"$set", bson.D{{"chromosomeOrder", chromosomeOrderExpr()}}}}})
+	if err != nil {
+		return nil, err
+	}
+	return db.client.Database(db.database).Collection("variants").Indexes().CreateMany(nil, Indexes)
+}
+
+// Search is the main method to search for variants.
+// Results in position order are paged by cursor if it is given, instead of skipping records.
+// Total is estimated from collection metadata and filtered variants are not counted after a cursor.
+func (db *DB) Search(i *search.Input) (*search.Response, error) {
+	expr, err := i.Expr()
+	if err != nil {
+		return nil, err
+	}
+	spans, err := db.maxSpans()
+	if err != nil {
+		return nil, err
+	}
+	filter := exprFilter(expr, spans)
+	fields, after, err := i.Paging()
+	if err != nil {
+		return nil, err
+	}
+
+	// one more variant tells whether there is a next page
+	cursored := search.IsPositionOrder(fields) && i.Length > 0
+	skip, limit := i.Start, i.Length
+	if after != nil {
+		skip = 0
+	}
+	if cursored {
+		limit++
+	}
+
+	var cur *mongo.Cursor
+	if len(fields) == 0 {
+		cur, err = db.client.Database(db.database).Collection("variants").
+			Find(nil, filter, &options.FindOptions{Limit: &limit, Skip: &skip})
+	} else {
+		pageFilter := filter
+		if after != nil {
+			pageFilter = bson.D{{"$and", bson.A{filter, cursorFilter(after)}}}
+		}
+		cur, err = db.client.Database(db.database).Collection("variants").
+			Aggregate(nil, sortPipeline(pageFilter, fields, skip, limit), options.Aggregate().SetAllowDiskUse(true))
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -84,17 +216,83 @@ func (db *DB) Search(i *search.Input) (*search.Response, error) {
 		variants = []*variant.Variant{}
 	}
 
-	total, err := db.client.Database(db.database).Collection("variants").CountDocuments(nil, bson.D{})
+	var next string
+	if cursored && int64(len(variants)) > i.Length {
+		variants = variants[:i.Length]
+		next = search.NewCursor(variants[len(variants)-1]).Encode()
+	}
+
+	total, err := db.client.Database(db.database).Collection("variants").EstimatedDocumentCount(nil)
 	if err != nil {
 		return nil, err
 	}
 
-	filtered, err := db.client.Database(db.database).Collection("variants").CountDocuments(nil, filter)
+	// counting filtered variants is as expensive as skipping them, pages after a cursor don't do it
+	var filtered int64
+	if after == nil {
+		filtered, err = db.client.Database(db.database).Collection("variants").CountDocuments(nil, filter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &search.Response{Draw: i.Draw, Variants: variants, RecordsTotal: total, RecordsFiltered: filtered, NextCursor: next}, nil
+}
+
+// Export streams variants that match input, in position order, from a MongoDB cursor.
+func (db *DB) Export(i *search.Input, fn func(v *variant.Variant) error) error {
+	expr, err := i.Expr()
 	if err != nil {
-		return nil, err
+		return err
+	}
+	spans, err := db.maxSpans()
+	if err != nil {
+		return err
+	}
+	pipeline := sortPipeline(exprFilter(expr, spans), []search.SortField{{Key: search.SortPosition}}, 0, 0)
+	cur, err := db.client.Database(db.database).Collection("variants").
+		Aggregate(nil, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return err
 	}
+	defer cur.Close(nil)
 
-	return &search.Response{Draw: i.Draw, Variants: variants, RecordsTotal: total, RecordsFiltered: filtered}, nil
+	for cur.Next(nil) {
+		var v variant.Variant
+		if err := cur.Decode(&v); err != nil {
+			return err
+		}
+		if err := fn(&v); err != nil {
+			return err
+		}
+	}
+	return cur.Err()
+}
+
+// CountAlleles counts alternate alleles of variants that match input with a single aggregation.
+func (db *DB) CountAlleles(i *search.Input) (int64, error) {
+	expr, err := i.Expr()
+	if err != nil {
+		return 0, err
+	}
+	spans, err := db.maxSpans()
+	if err != nil {
+		return 0, err
+	}
+	var counts []struct {
+		Alleles int64 `bson:"alleles"`
+	}
+	err = db.aggregate(&counts, bson.A{
+		bson.D{{"$match", exprFilter(expr, spans)}},
+		bson.D{{"$group", bson.D{
+			{"_id", nil},
+			{"alleles", bson.D{{"$sum", bson.D{{"$size", bson.D{{"$ifNull", bson.A{"$alternateBases", bson.A{}}}}}}}}},
+		}}},
+	})
+	if err != nil || len(counts) == 0 {
+		return 0, err
+	}
+	return counts[0].Alleles, nil
 }
 
 // Remove removes variants from database given a dataset ID and/or assembly ID.
@@ -0,0 +1,77 @@
+package mongo
+
+import (
+	"github.com/labbcb/brave/variant"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// span is the longest distance between start and end positions of variants of a reference sequence.
+// Range queries use it as lower bound of start positions, like the interval index of in-memory store.
+type span struct {
+	ReferenceName string `bson:"_id"`
+	MaxSpan       int32  `bson:"maxSpan"`
+}
+
+// saveSpans raises maximum spans of reference sequences to the spans of variants.
+// Spans are never lowered, when variants are removed or replaced range queries only scan more positions.
+func (db *DB) saveSpans(vs []*variant.Variant) error {
+	spans := make(map[string]int32)
+	for _, v := range vs {
+		if n, ok := spans[v.ReferenceName]; !ok || v.Stop()-v.Start > n {
+			spans[v.ReferenceName] = v.Stop() - v.Start
+		}
+	}
+
+	var models []mongo.WriteModel
+	for name, n := range spans {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.D{{"_id", name}}).
+			SetUpdate(bson.D{{"$max", bson.D{{"maxSpan", n}}}}).
+			SetUpsert(true))
+	}
+	if len(models) == 0 {
+		return nil
+	}
+	_, err := db.client.Database(db.database).Collection("spans").BulkWrite(nil, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// maxSpans returns maximum spans by reference name.
+func (db *DB) maxSpans() (map[string]int32, error) {
+	cur, err := db.client.Database(db.database).Collection("spans").Find(nil, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+	var spans []span
+	if err := cur.All(nil, &spans); err != nil {
+		return nil, err
+	}
+	m := make(map[string]int32, len(spans))
+	for _, s := range spans {
+		m[s.ReferenceName] = s.MaxSpan
+	}
+	return m, nil
+}
+
+// computeSpans stores maximum spans of variants saved before spans were kept, if there are none yet.
+// Variants without end position span a single base.
+func (db *DB) computeSpans() error {
+	collection := db.client.Database(db.database).Collection("spans")
+	n, err := collection.EstimatedDocumentCount(nil)
+	if err != nil || n > 0 {
+		return err
+	}
+	_, err = db.client.Database(db.database).Collection("variants").Aggregate(nil, bson.A{
+		bson.D{{"$group", bson.D{
+			{"_id", "$referenceName"},
+			{"maxSpan", bson.D{{"$max", bson.D{{"$subtract", bson.A{bson.D{{"$ifNull", bson.A{"$end", "$start"}}}, "$start"}}}}}},
+		}}},
+		bson.D{{"$merge", bson.D{
+			{"into", "spans"},
+			{"whenMatched", bson.A{bson.D{{"$set", bson.D{{"maxSpan", bson.D{{"$max", bson.A{"$maxSpan", "$$new.maxSpan"}}}}}}}}},
+		}}},
+	}, options.Aggregate().SetAllowDiskUse(true))
+	return err
+}
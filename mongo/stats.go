@@ -0,0 +1,115 @@
+package mongo
+
+import (
+	"github.com/labbcb/brave/dataset"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Stats summarizes variants per dataset and assembly, optionally restricted to a dataset and/or assembly.
+// It runs three aggregations over variants collection: totals, counts per chromosome and counts per variant type.
+func (db *DB) Stats(datasetID, assemblyID string) ([]*dataset.Stats, error) {
+	match := bson.D{}
+	if datasetID != "" {
+		match = append(match, bson.E{"datasetId", datasetID})
+	}
+	if assemblyID != "" {
+		match = append(match, bson.E{"assemblyId", assemblyID})
+	}
+
+	var totals []struct {
+		ID struct {
+			DatasetID  string `bson:"datasetId"`
+			AssemblyID string `bson:"assemblyId"`
+		} `bson:"_id"`
+		Variants     int64 `bson:"variants"`
+		TotalSamples int32 `bson:"totalSamples"`
+		ClinVar      int64 `bson:"clinvar"`
+	}
+	err := db.aggregate(&totals, bson.A{
+		bson.D{{"$match", match}},
+		bson.D{{"$group", bson.D{
+			{"_id", bson.D{{"datasetId", "$datasetId"}, {"assemblyId", "$assemblyId"}}},
+			{"variants", bson.D{{"$sum", 1}}},
+			{"totalSamples", bson.D{{"$max", "$totalSamples"}}},
+			{"clinvar", bson.D{{"$sum", bson.D{{"$cond", bson.A{
+				bson.D{{"$gt", bson.A{bson.D{{"$strLenCP", bson.D{{"$ifNull", bson.A{"$clnsig", ""}}}}}, 0}}}, 1, 0,
+			}}}}}},
+		}}},
+		bson.D{{"$sort", bson.D{{"_id.datasetId", 1}, {"_id.assemblyId", 1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct{ dataset, assembly string }
+	stats := make(map[key]*dataset.Stats)
+	ss := []*dataset.Stats{}
+	for _, t := range totals {
+		s := &dataset.Stats{
+			DatasetID:    t.ID.DatasetID,
+			AssemblyID:   t.ID.AssemblyID,
+			Variants:     t.Variants,
+			TotalSamples: t.TotalSamples,
+			ClinVar:      t.ClinVar,
+			Chromosomes:  make(map[string]int64),
+			Types:        make(map[string]int64),
+		}
+		stats[key{s.DatasetID, s.AssemblyID}] = s
+		ss = append(ss, s)
+	}
+
+	var counts []struct {
+		ID struct {
+			DatasetID  string `bson:"datasetId"`
+			AssemblyID string `bson:"assemblyId"`
+			Value      string `bson:"value"`
+		} `bson:"_id"`
+		Count int64 `bson:"count"`
+	}
+	err = db.aggregate(&counts, bson.A{
+		bson.D{{"$match", match}},
+		bson.D{{"$group", bson.D{
+			{"_id", bson.D{{"datasetId", "$datasetId"}, {"assemblyId", "$assemblyId"}, {"value", "$referenceName"}}},
+			{"count", bson.D{{"$sum", 1}}},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range counts {
+		if s, ok := stats[key{c.ID.DatasetID, c.ID.AssemblyID}]; ok {
+			s.Chromosomes[c.ID.Value] = c.Count
+		}
+	}
+
+	// each variant is counted once per distinct type among its alternate alleles
+	counts = nil
+	err = db.aggregate(&counts, bson.A{
+		bson.D{{"$match", match}},
+		bson.D{{"$project", bson.D{{"datasetId", 1}, {"assemblyId", 1}, {"type", bson.D{{"$setUnion", bson.A{"$type", bson.A{}}}}}}}},
+		bson.D{{"$unwind", "$type"}},
+		bson.D{{"$group", bson.D{
+			{"_id", bson.D{{"datasetId", "$datasetId"}, {"assemblyId", "$assemblyId"}, {"value", "$type"}}},
+			{"count", bson.D{{"$sum", 1}}},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range counts {
+		if s, ok := stats[key{c.ID.DatasetID, c.ID.AssemblyID}]; ok {
+			s.Types[c.ID.Value] = c.Count
+		}
+	}
+
+	return ss, nil
+}
+
+// aggregate runs pipeline over variants collection and decodes all results.
+func (db *DB) aggregate(results interface{}, pipeline bson.A) error {
+	cur, err := db.client.Database(db.database).Collection("variants").Aggregate(nil, pipeline)
+	if err != nil {
+		return err
+	}
+	return cur.All(nil, results)
+}
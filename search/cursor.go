@@ -0,0 +1,74 @@
+package search
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/labbcb/brave/variant"
+)
+
+// ErrInvalidCursor reports a malformed cursor or a cursor used with an order other than position.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is the position of the last variant of a page of results in position order.
+// Next page starts after it, so pages are stable when variants are added or removed.
+type Cursor struct {
+	ReferenceName string `json:"r"`
+	Start         int32  `json:"s"`
+	ID            string `json:"i"`
+}
+
+var positionOrder = []SortField{{SortPosition, false}}
+
+// NewCursor returns the cursor pointing after variant v.
+func NewCursor(v *variant.Variant) *Cursor {
+	return &Cursor{ReferenceName: v.ReferenceName, Start: v.Start, ID: v.ID}
+}
+
+// Encode returns the opaque cursor token.
+func (c *Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor parses an opaque cursor token.
+func DecodeCursor(token string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ReferenceName == "" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidCursor, token)
+	}
+	return &c, nil
+}
+
+// Before reports whether variant v comes after the cursor in position order.
+func (c *Cursor) Before(v *variant.Variant) bool {
+	return Less(&variant.Variant{ReferenceName: c.ReferenceName, Start: c.Start, ID: c.ID}, v, positionOrder)
+}
+
+// IsPositionOrder reports whether sort fields are position in ascending order, the order of cursor pagination.
+func IsPositionOrder(fields []SortField) bool {
+	return len(fields) == 1 && fields[0] == positionOrder[0]
+}
+
+// Paging returns sort fields and the decoded cursor, if any.
+// Cursor implies position order, other orders are rejected.
+func (i *Input) Paging() ([]SortField, *Cursor, error) {
+	fields, err := i.SortFields()
+	if err != nil || i.Cursor == "" {
+		return fields, nil, err
+	}
+	if len(fields) == 0 {
+		fields = positionOrder
+	}
+	if !IsPositionOrder(fields) {
+		return nil, nil, fmt.Errorf("%w: cursor requires position order", ErrInvalidCursor)
+	}
+	c, err := DecodeCursor(i.Cursor)
+	return fields, c, err
+}
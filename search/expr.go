@@ -0,0 +1,373 @@
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/labbcb/brave/variant"
+)
+
+// Expr is a node of a parsed query expression.
+type Expr interface {
+	// Match reports whether variant v satisfies the expression.
+	Match(v *variant.Variant) bool
+}
+
+// And matches variants that satisfy all expressions. Empty And matches all variants.
+type And []Expr
+
+// Or matches variants that satisfy at least one expression.
+type Or []Expr
+
+// Not matches variants that do not satisfy the expression.
+type Not struct {
+	Expr Expr
+}
+
+// Term matches variants like a query (gene:SCN1A, rs6054257, 1:1000-2000).
+type Term struct {
+	Query *Query
+}
+
+// Comparison compares a numeric field with a value (af<0.01, af.AFR<0.01).
+type Comparison struct {
+	Field      string  // af (any alternate allele), dp (median coverage) or gq (median genotype quality)
+	Population string  // group of samples of af, empty for all samples
+	Op         string  // <, <=, >, >= or =
+	Value      float64 // value compared to
+}
+
+// Numeric fields of comparisons.
+const (
+	AlleleFrequencyField = "af"
+	CoverageField        = "dp"
+	GenotypeQualityField = "gq"
+)
+
+// Match reports whether variant v satisfies all expressions.
+func (e And) Match(v *variant.Variant) bool {
+	for _, x := range e {
+		if !x.Match(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Match reports whether variant v satisfies at least one expression.
+func (e Or) Match(v *variant.Variant) bool {
+	for _, x := range e {
+		if x.Match(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether variant v does not satisfy the expression.
+func (e *Not) Match(v *variant.Variant) bool {
+	return !e.Expr.Match(v)
+}
+
+// Match reports whether variant v satisfies the query.
+func (e *Term) Match(v *variant.Variant) bool {
+	return e.Query.Match(v)
+}
+
+// Match reports whether variant v satisfies the comparison.
+// Variants without the compared value never match.
+// Allele frequencies are stored as float32, so they are compared with the value rounded to float32 (see AlleleFrequencyValue).
+func (e *Comparison) Match(v *variant.Variant) bool {
+	switch e.Field {
+	case AlleleFrequencyField:
+		for _, af := range alleleFrequency(v, e.Population) {
+			if compare(float64(af), e.Op, e.AlleleFrequencyValue()) {
+				return true
+			}
+		}
+		return false
+	case CoverageField:
+		return v.Coverage != nil && compare(v.Coverage.Median, e.Op, e.Value)
+	case GenotypeQualityField:
+		return v.GenotypeQuality != nil && compare(v.GenotypeQuality.Median, e.Op, e.Value)
+	}
+	return false
+}
+
+// AlleleFrequencyValue returns the value rounded to float32 precision of stored allele frequencies,
+// so af=0.01 matches a stored 0.01 and af<0.01 does not.
+func (e *Comparison) AlleleFrequencyValue() float64 {
+	return float64(float32(e.Value))
+}
+
+func compare(x float64, op string, y float64) bool {
+	switch op {
+	case "<":
+		return x < y
+	case "<=":
+		return x <= y
+	case ">":
+		return x > y
+	case ">=":
+		return x >= y
+	case "=":
+		return x == y
+	}
+	return false
+}
+
+// SyntaxError reports an invalid query expression. Position is 1-based.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Pos, e.Msg)
+}
+
+// ParseExpr parses a query expression:
+//
+//	gene:SCN1A AND af<0.01 AND (type:missense_variant OR clnsig:Pathogenic)
+//
+// Terms are field:value (gene, id, dataset, assembly, region, ref, alt, type, clnsig),
+// numeric comparisons (af, dp and gq with <, <=, >, >= or =; af.AFR compares allele frequency of a population)
+// or any text accepted by Parse (SCN1A, rs6054257, 1:1000-2000, 1:12345:A>G).
+// Operators are NOT, AND and OR, in order of precedence, and parentheses group expressions.
+// Adjacent terms are combined with AND.
+func ParseExpr(text string) (Expr, error) {
+	p := &parser{tokens: tokenize(text), end: len(text) + 1}
+	if len(p.tokens) == 0 {
+		return nil, &SyntaxError{1, "empty expression"}
+	}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t != nil {
+		return nil, &SyntaxError{t.pos, fmt.Sprintf("unexpected %q", t.text)}
+	}
+	return e, nil
+}
+
+// token is a word, operator or parenthesis at a 1-based position.
+type token struct {
+	text string
+	pos  int
+}
+
+func tokenize(text string) []*token {
+	var tokens []*token
+	start := -1
+	for i, r := range text {
+		if unicode.IsSpace(r) || r == '(' || r == ')' {
+			if start >= 0 {
+				tokens = append(tokens, &token{text[start:i], start + 1})
+				start = -1
+			}
+			if r == '(' || r == ')' {
+				tokens = append(tokens, &token{string(r), i + 1})
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, &token{text[start:], start + 1})
+	}
+	return tokens
+}
+
+type parser struct {
+	tokens []*token
+	i      int
+	end    int // position after the last character
+}
+
+func (p *parser) peek() *token {
+	if p.i < len(p.tokens) {
+		return p.tokens[p.i]
+	}
+	return nil
+}
+
+// keyword reports whether next token is the operator kw, consuming it if so.
+func (p *parser) keyword(kw string) bool {
+	if t := p.peek(); t != nil && strings.EqualFold(t.text, kw) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) or() (Expr, error) {
+	e, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	or := Or{e}
+	for p.keyword("OR") {
+		e, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, e)
+	}
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+func (p *parser) and() (Expr, error) {
+	e, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	and := And{e}
+	for {
+		explicit := p.keyword("AND")
+		t := p.peek()
+		if t == nil || t.text == ")" || strings.EqualFold(t.text, "OR") {
+			if explicit {
+				return nil, p.expected("term after AND")
+			}
+			break
+		}
+		e, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, e)
+	}
+	if len(and) == 1 {
+		return and[0], nil
+	}
+	return and, nil
+}
+
+func (p *parser) unary() (Expr, error) {
+	if p.keyword("NOT") {
+		e, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{e}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (Expr, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, p.expected("term")
+	}
+	switch {
+	case t.text == "(":
+		p.i++
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if c := p.peek(); c == nil || c.text != ")" {
+			return nil, p.expected(`")"`)
+		}
+		p.i++
+		return e, nil
+	case t.text == ")", strings.EqualFold(t.text, "AND"), strings.EqualFold(t.text, "OR"):
+		return nil, &SyntaxError{t.pos, fmt.Sprintf("unexpected %q, expected term", t.text)}
+	}
+	p.i++
+	return parseTerm(t)
+}
+
+// expected reports a missing element at current token or at the end of expression.
+func (p *parser) expected(what string) error {
+	if t := p.peek(); t != nil {
+		return &SyntaxError{t.pos, fmt.Sprintf("unexpected %q, expected %s", t.text, what)}
+	}
+	return &SyntaxError{p.end, "unexpected end of expression, expected " + what}
+}
+
+var comparisonOperators = []string{"<=", ">=", "<", ">", "="}
+
+// parseTerm parses field:value, numeric comparisons or text accepted by Parse.
+func parseTerm(t *token) (Expr, error) {
+	name := t.text
+	if i := strings.IndexFunc(name, func(r rune) bool { return !unicode.IsLetter(r) }); i >= 0 {
+		name = name[:i]
+	}
+	rest := t.text[len(name):]
+	field := strings.ToLower(name)
+
+	switch field {
+	case AlleleFrequencyField, CoverageField, GenotypeQualityField:
+		var population string
+		if field == AlleleFrequencyField && strings.HasPrefix(rest, ".") {
+			population = rest[1:]
+			if i := strings.IndexFunc(population, func(r rune) bool { return !variant.PopulationName.MatchString(string(r)) }); i >= 0 {
+				population = population[:i]
+			}
+			if population == "" {
+				return nil, &SyntaxError{t.pos + len(name) + 1, "missing population of " + name}
+			}
+			rest = rest[1+len(population):]
+		}
+		for _, op := range comparisonOperators {
+			if strings.HasPrefix(rest, op) {
+				value, err := strconv.ParseFloat(rest[len(op):], 64)
+				if err != nil {
+					return nil, &SyntaxError{t.pos + len(t.text) - len(rest) + len(op), fmt.Sprintf("invalid number %q", rest[len(op):])}
+				}
+				return &Comparison{Field: field, Population: population, Op: op, Value: value}, nil
+			}
+		}
+		return nil, &SyntaxError{t.pos + len(t.text) - len(rest), fmt.Sprintf("expected comparison operator after %s", t.text[:len(t.text)-len(rest)])}
+	}
+
+	if name == "" || !strings.HasPrefix(rest, ":") {
+		q := Parse(t.text)
+		if q.ReferenceName == "" && q.SnpID == "" && q.GeneSymbol == "" {
+			return nil, &SyntaxError{t.pos, fmt.Sprintf("invalid term %q", t.text)}
+		}
+		return &Term{q}, nil
+	}
+
+	value := rest[1:]
+	if value == "" {
+		return nil, &SyntaxError{t.pos + len(t.text), fmt.Sprintf("missing value of %s", name)}
+	}
+	q := new(Query)
+	switch field {
+	case "gene":
+		q.GeneSymbol = value
+	case "id", "rs", "snp":
+		q.SnpID = value
+	case "dataset":
+		q.DatasetID = value
+	case "assembly":
+		q.AssemblyID = value
+	case "ref":
+		q.ReferenceBases = strings.ToUpper(value)
+	case "alt":
+		q.AlternateBases = strings.ToUpper(value)
+	case "type":
+		q.Types = []string{value}
+	case "clnsig":
+		q.ClinicalSignificance = []string{value}
+	case "region":
+		q = Parse(value)
+		if q.ReferenceName == "" {
+			return nil, &SyntaxError{t.pos + len(name) + 1, fmt.Sprintf("invalid region %q", value)}
+		}
+	default:
+		// genomic positions and alleles start with chromosome names like X
+		if q := Parse(t.text); q.ReferenceName != "" {
+			return &Term{q}, nil
+		}
+		return nil, &SyntaxError{t.pos, fmt.Sprintf("unknown field %q", name)}
+	}
+	return &Term{q}, nil
+}
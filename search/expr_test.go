@@ -0,0 +1,103 @@
+package search
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/labbcb/brave/variant"
+)
+
+func TestParseExpr(t *testing.T) {
+	variants := []*variant.Variant{
+		{ID: "0", ReferenceName: "2", Start: 166848646, ReferenceBases: "G", AlternateBases: []string{"A"}, GeneSymbol: []string{"SCN1A"},
+			AlleleFrequency: []float32{0.001}, Type: []string{"missense_variant"}, Coverage: &variant.Distribution{Median: 30}},
+		{ID: "1", ReferenceName: "2", Start: 166848700, ReferenceBases: "C", AlternateBases: []string{"T"}, GeneSymbol: []string{"SCN1A"},
+			AlleleFrequency: []float32{0.2}, Type: []string{"synonymous_variant"}, CLNSIG: "Benign"},
+		{ID: "2", ReferenceName: "2", Start: 166848800, ReferenceBases: "T", AlternateBases: []string{"C"}, GeneSymbol: []string{"SCN1A"},
+			AlleleFrequency: []float32{0.005}, Type: []string{"stop_gained"}, CLNSIG: "Pathogenic", SnpIds: []string{"rs121917"}},
+		{ID: "3", ReferenceName: "20", Start: 14370, ReferenceBases: "G", AlternateBases: []string{"A"}, GeneSymbol: []string{"OTHER"},
+			AlleleFrequency: []float32{0.5}, Type: []string{"missense_variant"},
+			Populations: variant.Populations{"AFR": {AlleleFrequency: []float32{0.005}}, "EUR": {AlleleFrequency: []float32{0.6}}}},
+	}
+
+	ts := []struct {
+		text string
+		want string
+	}{
+		{"gene:SCN1A AND af<0.01 AND (type:missense_variant OR clnsig:Pathogenic)", "[0 2]"},
+		{"SCN1A af<0.01", "[0 2]"},
+		{"scn1a", "[]"},
+		{"gene:SCN1A AND NOT type:missense_variant", "[1 2]"},
+		{"type:missense_variant OR clnsig:benign AND af>0.1", "[0 1 3]"},
+		{"(type:missense_variant OR clnsig:benign) AND af>0.1", "[1 3]"},
+		{"not not dp>=30", "[0]"},
+		{"20:14370:G>A or rs121917", "[2 3]"},
+		{"region:2:166848600-166848750 alt:t", "[1]"},
+		{"af=0.5", "[3]"},
+		{"af.AFR<0.01", "[3]"},
+		{"af>0.1 AND NOT af.EUR>=0.6", "[1]"},
+	}
+	for _, tc := range ts {
+		e, err := ParseExpr(tc.text)
+		if err != nil {
+			t.Errorf("%s: %v", tc.text, err)
+			continue
+		}
+		var ids []string
+		for _, v := range variants {
+			if e.Match(v) {
+				ids = append(ids, v.ID)
+			}
+		}
+		if got := fmt.Sprint(ids); got != tc.want {
+			t.Errorf("%s: want %s, got %s", tc.text, tc.want, got)
+		}
+	}
+}
+
+func TestAlleleFrequencyComparison(t *testing.T) {
+	v := &variant.Variant{AlleleFrequency: []float32{0.01}}
+	ts := map[string]bool{
+		"af<0.01":  false,
+		"af<=0.01": true,
+		"af=0.01":  true,
+		"af>=0.01": true,
+		"af>0.01":  false,
+		"af<0.011": true,
+		"af>0.009": true,
+	}
+	for text, want := range ts {
+		e, err := ParseExpr(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := e.Match(v); got != want {
+			t.Errorf("%s: want %v, got %v", text, want, got)
+		}
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	ts := map[string]string{
+		"":                    "syntax error at position 1: empty expression",
+		"gene:SCN1A AND":      `syntax error at position 15: unexpected end of expression, expected term after AND`,
+		"(gene:SCN1A":         `syntax error at position 12: unexpected end of expression, expected ")"`,
+		"gene:SCN1A)":         `syntax error at position 11: unexpected ")"`,
+		"OR gene:SCN1A":       `syntax error at position 1: unexpected "OR", expected term`,
+		"gene:SCN1A AND af<x": `syntax error at position 19: invalid number "x"`,
+		"af:0.1":              "syntax error at position 3: expected comparison operator after af",
+		"af.AFR:0.1":          "syntax error at position 7: expected comparison operator after af.AFR",
+		"af.$x<0.1":           "syntax error at position 4: missing population of af",
+		"foo:bar":             `syntax error at position 1: unknown field "foo"`,
+		"gene:":               "syntax error at position 6: missing value of gene",
+		"gene:SCN1A AND a<b":  `syntax error at position 16: invalid term "a<b"`,
+		"region:SCN1A":        `syntax error at position 8: invalid region "SCN1A"`,
+		"gene:SCN1A AND (OR)": `syntax error at position 17: unexpected "OR", expected term`,
+	}
+	for text, want := range ts {
+		_, err := ParseExpr(text)
+		if err == nil || err.Error() != want {
+			t.Errorf("%q: want error %s, got %v", text, want, err)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package search
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/labbcb/brave/variant"
+)
+
+// ErrInvalidPopulation reports a population name that is not valid (see variant.PopulationName).
+var ErrInvalidPopulation = errors.New("invalid population")
+
+// ErrUnrestrictedPopulation reports population allele frequency filters without a dataset, region, gene or dbSNP ID condition.
+// Population frequencies are not indexed, so they would be compared for every stored variant.
+var ErrUnrestrictedPopulation = errors.New("population allele frequency filters require a dataset, genomic position or range, gene or dbSNP ID")
+
+// clinicalSignificanceSeparators split multiple CLNSIG values (Pathogenic/Likely_pathogenic,risk_factor).
+const clinicalSignificanceSeparators = ",/|"
+
+// ClinicalSignificancePattern returns a regular expression, to be matched case-insensitively,
+// that matches CLNSIG values containing term.
+func ClinicalSignificancePattern(term string) string {
+	sep := regexp.QuoteMeta(clinicalSignificanceSeparators)
+	return "(^|[" + sep + "])" + regexp.QuoteMeta(term) + "($|[" + sep + "])"
+}
+
+// matchFilters reports whether variant v satisfies frequency, type, clinical significance and quality filters.
+// If the query has an alternate allele, frequency filters apply to that allele only.
+func (q *Query) matchFilters(v *variant.Variant) bool {
+	if q.MinAlleleFrequency != 0 || q.MaxAlleleFrequency != 0 {
+		afs := alleleFrequency(v, q.Population)
+		if q.AlternateBases != "" {
+			afs = alleleValue(afs, v.AlleleIndex(q.AlternateBases))
+		}
+		if !q.matchAlleleFrequency(afs) {
+			return false
+		}
+	}
+	if len(q.Types) > 0 && !containsAny(v.Type, q.Types) {
+		return false
+	}
+	if len(q.ClinicalSignificance) > 0 && !matchClinicalSignificance(v.CLNSIG, q.ClinicalSignificance) {
+		return false
+	}
+	if q.MinCoverage != 0 && (v.Coverage == nil || v.Coverage.Median < q.MinCoverage) {
+		return false
+	}
+	if q.MinGenotypeQuality != 0 && (v.GenotypeQuality == nil || v.GenotypeQuality.Median < q.MinGenotypeQuality) {
+		return false
+	}
+	return true
+}
+
+// matchAlleleFrequency reports whether any allele frequency is within minimum and maximum.
+func (q *Query) matchAlleleFrequency(afs []float32) bool {
+	for _, af := range afs {
+		if af >= q.MinAlleleFrequency && (q.MaxAlleleFrequency == 0 || af <= q.MaxAlleleFrequency) {
+			return true
+		}
+	}
+	return false
+}
+
+// alleleFrequency returns allele frequencies of a population, or of all samples if population is empty.
+func alleleFrequency(v *variant.Variant, population string) []float32 {
+	if population == "" {
+		return v.AlleleFrequency
+	}
+	if p := v.Populations[population]; p != nil {
+		return p.AlleleFrequency
+	}
+	return nil
+}
+
+// alleleValue returns the value of the i-th alternate allele, or nil if there is no such allele.
+func alleleValue(afs []float32, i int) []float32 {
+	if i < 0 || i >= len(afs) {
+		return nil
+	}
+	return afs[i : i+1]
+}
+
+func matchClinicalSignificance(clnsig string, terms []string) bool {
+	values := strings.FieldsFunc(clnsig, func(r rune) bool { return strings.ContainsRune(clinicalSignificanceSeparators, r) })
+	for _, value := range values {
+		for _, term := range terms {
+			if strings.EqualFold(value, term) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsAny(xs, ys []string) bool {
+	for _, y := range ys {
+		if contains(xs, y) {
+			return true
+		}
+	}
+	return false
+}
+
+// indexed reports whether query has a condition served by an index: dataset, genomic position or range, gene or dbSNP ID.
+func (q *Query) indexed() bool {
+	return q.DatasetID != "" || q.GeneSymbol != "" || q.SnpID != "" || q.ReferenceName != "" && q.Start != 0
+}
+
+// populationFilter reports whether expression filters allele frequencies of a population.
+func populationFilter(e Expr) bool {
+	switch x := e.(type) {
+	case And:
+		for _, y := range x {
+			if populationFilter(y) {
+				return true
+			}
+		}
+	case Or:
+		for _, y := range x {
+			if populationFilter(y) {
+				return true
+			}
+		}
+	case *Not:
+		return populationFilter(x.Expr)
+	case *Term:
+		return x.Query.Population != "" && (x.Query.MinAlleleFrequency != 0 || x.Query.MaxAlleleFrequency != 0)
+	case *Comparison:
+		return x.Field == AlleleFrequencyField && x.Population != ""
+	}
+	return false
+}
+
+// indexed reports whether every variant that matches expression satisfies an indexed query condition.
+func indexed(e Expr) bool {
+	switch x := e.(type) {
+	case And:
+		for _, y := range x {
+			if indexed(y) {
+				return true
+			}
+		}
+	case Or:
+		for _, y := range x {
+			if !indexed(y) {
+				return false
+			}
+		}
+		return len(x) > 0
+	case *Term:
+		return x.Query.indexed()
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/labbcb/brave/variant"
+)
+
+func TestMatchFilters(t *testing.T) {
+	v := &variant.Variant{
+		AlternateBases:  []string{"G", "T"},
+		AlleleFrequency: []float32{0.005, 0.2},
+		Type:            []string{"missense_variant", "synonymous_variant"},
+		CLNSIG:          "Pathogenic/Likely_pathogenic,risk_factor",
+		Coverage:        &variant.Distribution{Median: 25},
+		Populations:     variant.Populations{"AFR": {AlleleFrequency: []float32{0.05, 0.3}}},
+	}
+
+	ts := []struct {
+		query *Query
+		want  bool
+	}{
+		{&Query{}, true},
+		{&Query{MaxAlleleFrequency: 0.01}, true},
+		{&Query{MinAlleleFrequency: 0.01, MaxAlleleFrequency: 0.1}, false},
+		{&Query{MinAlleleFrequency: 0.1}, true},
+		{&Query{MaxAlleleFrequency: 0.01, Population: "AFR"}, false},
+		{&Query{MinAlleleFrequency: 0.01, MaxAlleleFrequency: 0.1, Population: "AFR"}, true},
+		{&Query{MinAlleleFrequency: 0.01, Population: "EUR"}, false},
+		{&Query{AlternateBases: "G", MinAlleleFrequency: 0.1}, false},
+		{&Query{AlternateBases: "T", MinAlleleFrequency: 0.1}, true},
+		{&Query{AlternateBases: "T", MaxAlleleFrequency: 0.1, Population: "AFR"}, false},
+		{&Query{AlternateBases: "C", MaxAlleleFrequency: 0.1}, false},
+		{&Query{Types: []string{"stop_gained", "missense_variant"}}, true},
+		{&Query{Types: []string{"stop_gained"}}, false},
+		{&Query{ClinicalSignificance: []string{"pathogenic"}}, true},
+		{&Query{ClinicalSignificance: []string{"Risk_factor"}}, true},
+		{&Query{ClinicalSignificance: []string{"Benign"}}, false},
+		{&Query{MinCoverage: 20}, true},
+		{&Query{MinCoverage: 30}, false},
+		{&Query{MinGenotypeQuality: 10}, false},
+	}
+	for _, tc := range ts {
+		if got := tc.query.Match(v); got != tc.want {
+			t.Errorf("%+v: want %v, got %v", tc.query, tc.want, got)
+		}
+	}
+}
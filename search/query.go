@@ -3,6 +3,9 @@ package search
 import (
 	"regexp"
 	"strconv"
+	"strings"
+
+	"github.com/labbcb/brave/variant"
 )
 
 var (
@@ -10,6 +13,8 @@ var (
 	GenomicRange = regexp.MustCompile(`^\s*([1-9]|1[0-9]|2[0-2]|[XY])\s*:\s*(\d+)\s*-\s*(\d+)\s*$`)
 	// GenomicPosition is a regex that matches a genomic position, 1:1000
 	GenomicPosition = regexp.MustCompile(`^\s*([1-9]|1[0-9]|2[0-2]|[XY])\s*:\s*(\d+)\s*$`)
+	// GenomicAllele is a regex that matches an allele at a genomic position, 1:12345:A>G or 1-12345-A-G
+	GenomicAllele = regexp.MustCompile(`^\s*([1-9]|1[0-9]|2[0-2]|[XY])\s*[:-]\s*(\d+)\s*[:-]\s*([ACGTNacgtn]+)\s*[>-]\s*([ACGTNacgtn]+|\*)\s*$`)
 	// SnpID is a regex that matches dbSNP ID, rs35735053
 	SnpID = regexp.MustCompile(`^\s*(rs\d+)\s*$`)
 	// GeneSymbol is a regex that matches gene name, SCN1A
@@ -19,18 +24,36 @@ var (
 // Query contains optional parameters for filtering variants.
 // All fields may be omitted meaning that matches with all variants present in the database
 type Query struct {
-	SnpID         string `json:"snpId"`         // external variant id, normally from dbSNP database (rs35735053)
-	AssemblyID    string `json:"assemblyId"`    // reference genome version (GRCh38)
-	DatasetID     string `json:"datasetId"`     // call set id (bipmed-wes-phase2)
-	ReferenceName string `json:"referenceName"` // chromosome name (chr1, 1)
-	Start         int32  `json:"start"`         // start position (7737651)
-	End           int32  `json:"end"`           // end position (70000)
-	GeneSymbol    string `json:"geneSymbol"`    // gene symbol (SCN1A)
+	SnpID          string `json:"snpId"`          // external variant id, normally from dbSNP database (rs35735053)
+	AssemblyID     string `json:"assemblyId"`     // reference genome version (GRCh38)
+	DatasetID      string `json:"datasetId"`      // call set id (bipmed-wes-phase2)
+	ReferenceName  string `json:"referenceName"`  // chromosome name (chr1, 1)
+	Start          int32  `json:"start"`          // start position (7737651)
+	End            int32  `json:"end"`            // end position (70000)
+	Contained      bool   `json:"contained"`      // range matches variants inside it instead of overlapping it
+	GeneSymbol     string `json:"geneSymbol"`     // gene symbol (SCN1A)
+	ReferenceBases string `json:"referenceBases"` // reference allele (A)
+	AlternateBases string `json:"alternateBases"` // one of the alternate alleles (G)
+
+	// filters, zero values are ignored
+	// allele frequency filters apply to AlternateBases if set, otherwise to any alternate allele
+	MinAlleleFrequency   float32  `json:"minAlleleFrequency,omitempty"`   // minimum allele frequency of any alternate allele (0.001)
+	MaxAlleleFrequency   float32  `json:"maxAlleleFrequency,omitempty"`   // maximum allele frequency of the same alternate allele (0.01)
+	Population           string   `json:"population,omitempty"`           // group of samples whose allele frequency is filtered (AFR)
+	Types                []string `json:"types,omitempty"`                // any of variant types (missense_variant)
+	ClinicalSignificance []string `json:"clinicalSignificance,omitempty"` // any of clinical significance terms (Pathogenic)
+	MinCoverage          float64  `json:"minCoverage,omitempty"`          // minimum median coverage (20)
+	MinGenotypeQuality   float64  `json:"minGenotypeQuality,omitempty"`   // minimum median genotype quality (30)
 }
 
 // Parse parses text to a query
 func Parse(text string) *Query {
-	xs := GenomicRange.FindStringSubmatch(text)
+	xs := GenomicAllele.FindStringSubmatch(text)
+	if xs != nil {
+		return &Query{ReferenceName: xs[1], Start: mustBeInt32(xs[2]),
+			ReferenceBases: strings.ToUpper(xs[3]), AlternateBases: strings.ToUpper(xs[4])}
+	}
+	xs = GenomicRange.FindStringSubmatch(text)
 	if xs != nil {
 		return &Query{ReferenceName: xs[1], Start: mustBeInt32(xs[2]), End: mustBeInt32(xs[3])}
 	}
@@ -56,3 +79,48 @@ func mustBeInt32(s string) int32 {
 	}
 	return int32(i)
 }
+
+// Match reports whether variant v satisfies the query.
+// It follows the same semantics as the MongoDB filter built from the query,
+// so it can be used by storage backends that evaluate queries in memory.
+func (q *Query) Match(v *variant.Variant) bool {
+	if q.AssemblyID != "" && v.AssemblyID != q.AssemblyID {
+		return false
+	}
+	if q.GeneSymbol != "" && !contains(v.GeneSymbol, q.GeneSymbol) {
+		return false
+	}
+	if q.DatasetID != "" && v.DatasetID != q.DatasetID {
+		return false
+	}
+	if q.SnpID != "" && !contains(v.SnpIds, q.SnpID) {
+		return false
+	}
+	if q.ReferenceBases != "" && v.ReferenceBases != q.ReferenceBases {
+		return false
+	}
+	if q.AlternateBases != "" && !contains(v.AlternateBases, q.AlternateBases) {
+		return false
+	}
+	if !q.matchFilters(v) {
+		return false
+	}
+	if q.ReferenceName != "" && q.Start != 0 && q.End != 0 {
+		if q.Contained {
+			return v.ReferenceName == q.ReferenceName && v.Start >= q.Start && v.Stop() <= q.End
+		}
+		return v.ReferenceName == q.ReferenceName && v.Start <= q.End && v.Stop() >= q.Start
+	} else if q.ReferenceName != "" && q.Start != 0 {
+		return v.ReferenceName == q.ReferenceName && v.Start == q.Start
+	}
+	return true
+}
+
+func contains(xs []string, s string) bool {
+	for _, x := range xs {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -11,6 +11,9 @@ func TestParse(t *testing.T) {
 		"1:65000-70000": {ReferenceName: "1", Start: 65000, End: 70000},
 		"1:7737651":     {ReferenceName: "1", Start: 7737651},
 		"rs35735053":    {SnpID: "rs35735053"},
+		"1:12345:A>G":   {ReferenceName: "1", Start: 12345, ReferenceBases: "A", AlternateBases: "G"},
+		"1-12345-a-gt":  {ReferenceName: "1", Start: 12345, ReferenceBases: "A", AlternateBases: "GT"},
+		"X:100:AC>*":    {ReferenceName: "X", Start: 100, ReferenceBases: "AC", AlternateBases: "*"},
 		"":              {},
 	}
 
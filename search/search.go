@@ -1,22 +1,68 @@
 package search
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/labbcb/brave/variant"
 )
 
 // Response is the response object
 type Response struct {
-	Draw            int                `json:"draw"`            // the draw counter that this object is a response to
-	RecordsTotal    int64              `json:"recordsTotal"`    // total records, before filtering
-	RecordsFiltered int64              `json:"recordsFiltered"` // total records, after filtering
-	Error           string             `json:"error"`           // list of variants that matched one (or more) search query
-	Variants        []*variant.Variant `json:"data"`            // if an error occurs during the running of the server-side processing
+	Draw            int                `json:"draw"`                 // the draw counter that this object is a response to
+	RecordsTotal    int64              `json:"recordsTotal"`         // total records, before filtering
+	RecordsFiltered int64              `json:"recordsFiltered"`      // total records, after filtering, not counted in pages after a cursor
+	Error           string             `json:"error"`                // list of variants that matched one (or more) search query
+	Variants        []*variant.Variant `json:"data"`                 // if an error occurs during the running of the server-side processing
+	NextCursor      string             `json:"nextCursor,omitempty"` // cursor of next page if results are in position order and there are more of them
 }
 
 // Input it the request object
 type Input struct {
-	Draw    int      `json:"draw"`    // draw counter
-	Start   int64    `json:"start"`   // paging first record indicator
-	Length  int64    `json:"length"`  // number of records that the table can display in the current draw
-	Queries []*Query `json:"queries"` // list of queries
+	Draw    int      `json:"draw"`              // draw counter
+	Start   int64    `json:"start"`             // paging first record indicator
+	Length  int64    `json:"length"`            // number of records that the table can display in the current draw
+	Queries []*Query `json:"queries"`           // list of queries
+	Q       string   `json:"q,omitempty"`       // query expression, see ParseExpr
+	Sort    string   `json:"sort,omitempty"`    // sort keys, see SortFields
+	Order   []Order  `json:"order,omitempty"`   // DataTables column ordering, used if Sort is empty
+	Columns []Column `json:"columns,omitempty"` // DataTables columns referenced by Order
+	Cursor  string   `json:"cursor,omitempty"`  // next page of position ordered results, Start is ignored
+}
+
+// Expr combines input queries and query expression.
+// Variants must satisfy at least one of the queries, if any, and the expression, if any.
+// Queries with invalid population names are reported as ErrInvalidPopulation, and population allele frequency
+// filters that are not combined with an indexed condition (dataset, region, gene or dbSNP ID) as ErrUnrestrictedPopulation.
+// An input without queries and expression results in an empty And that matches all variants.
+func (i *Input) Expr() (Expr, error) {
+	e := And{}
+	if len(i.Queries) > 0 {
+		or := make(Or, len(i.Queries))
+		for n, q := range i.Queries {
+			if q.Population != "" && !variant.PopulationName.MatchString(q.Population) {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidPopulation, q.Population)
+			}
+			or[n] = &Term{q}
+		}
+		e = append(e, or)
+	}
+	if strings.TrimSpace(i.Q) != "" {
+		x, err := ParseExpr(i.Q)
+		if err != nil {
+			return nil, err
+		}
+		e = append(e, x)
+	}
+	if populationFilter(e) && !indexed(e) {
+		return nil, ErrUnrestrictedPopulation
+	}
+	return e, nil
+}
+
+// Match reports whether variant v satisfies at least one of the input queries and the query expression.
+// An input without queries and expression matches all variants, an invalid expression matches none.
+func (i *Input) Match(v *variant.Variant) bool {
+	e, err := i.Expr()
+	return err == nil && e.Match(v)
 }
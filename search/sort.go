@@ -0,0 +1,224 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/labbcb/brave/variant"
+)
+
+// Sort keys.
+const (
+	SortPosition        = "position"    // natural chromosome order then start position
+	SortAlleleFrequency = "af"          // allele frequency
+	SortGene            = "gene"        // gene symbol
+	SortSampleCount     = "sampleCount" // number of samples with data
+	SortCoverage        = "coverage"    // median coverage
+)
+
+// sortKeys maps sort names and DataTables column names (variant JSON fields) to sort keys.
+var sortKeys = map[string]string{
+	SortPosition:        SortPosition,
+	"referenceName":     SortPosition,
+	"start":             SortPosition,
+	SortAlleleFrequency: SortAlleleFrequency,
+	"alleleFrequency":   SortAlleleFrequency,
+	SortGene:            SortGene,
+	"geneSymbol":        SortGene,
+	SortSampleCount:     SortSampleCount,
+	SortCoverage:        SortCoverage,
+	"coverage.median":   SortCoverage,
+}
+
+// ErrInvalidSort reports an unknown sort key or column.
+var ErrInvalidSort = errors.New("invalid sort")
+
+// Chromosomes lists chromosome names in natural order. Other names come after them, in lexical order.
+var Chromosomes = []string{
+	"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11",
+	"12", "13", "14", "15", "16", "17", "18", "19", "20", "21", "22",
+	"X", "Y", "MT", "M",
+}
+
+var chromosomeOrder = func() map[string]int {
+	m := make(map[string]int, len(Chromosomes))
+	for i, name := range Chromosomes {
+		m[name] = i
+	}
+	return m
+}()
+
+// ChromosomeOrder returns the index of reference name in natural chromosome order,
+// or the number of known chromosomes for other names.
+func ChromosomeOrder(name string) int {
+	if i, ok := chromosomeOrder[name]; ok {
+		return i
+	}
+	return len(Chromosomes)
+}
+
+// Order is a DataTables ordering instruction: column index and direction (asc or desc).
+type Order struct {
+	Column int    `json:"column"`
+	Dir    string `json:"dir"`
+}
+
+// Column is a DataTables column. Name, or Data if name is empty, is a sort name or variant JSON field.
+type Column struct {
+	Data      string `json:"data"`
+	Name      string `json:"name"`
+	Orderable bool   `json:"orderable"`
+}
+
+// SortField is a sort key and direction.
+type SortField struct {
+	Key  string
+	Desc bool
+}
+
+// SortFields returns sort fields of input, from Sort if set, otherwise from DataTables Order and Columns.
+// Sort is a comma-separated list of keys (position, af, gene, sampleCount, coverage), descending if prefixed with "-".
+// Repeated keys are ignored.
+func (i *Input) SortFields() ([]SortField, error) {
+	var fields []SortField
+	seen := make(map[string]bool)
+	add := func(name string, desc bool) error {
+		key, ok := sortKeys[name]
+		if !ok {
+			return fmt.Errorf("%w: unknown key %q", ErrInvalidSort, name)
+		}
+		if !seen[key] {
+			seen[key] = true
+			fields = append(fields, SortField{key, desc})
+		}
+		return nil
+	}
+
+	if strings.TrimSpace(i.Sort) != "" {
+		for _, name := range strings.Split(i.Sort, ",") {
+			name = strings.TrimSpace(name)
+			desc := strings.HasPrefix(name, "-")
+			if err := add(strings.TrimLeft(name, "+-"), desc); err != nil {
+				return nil, err
+			}
+		}
+		return fields, nil
+	}
+
+	for _, o := range i.Order {
+		if o.Column < 0 || o.Column >= len(i.Columns) {
+			return nil, fmt.Errorf("%w: column %d does not exist", ErrInvalidSort, o.Column)
+		}
+		c := i.Columns[o.Column]
+		name := c.Name
+		if name == "" {
+			name = c.Data
+		}
+		if o.Dir != "" && o.Dir != "asc" && o.Dir != "desc" {
+			return nil, fmt.Errorf("%w: direction %q", ErrInvalidSort, o.Dir)
+		}
+		if err := add(name, o.Dir == "desc"); err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
+
+// Less reports whether variant a comes before b according to sort fields.
+// It follows MongoDB ordering: arrays compare by their smallest element in ascending order
+// and by their largest element in descending order, missing values come first.
+// Ties are broken by variant ID.
+func Less(a, b *variant.Variant, fields []SortField) bool {
+	for _, f := range fields {
+		c := compareBy(a, b, f)
+		if f.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c < 0
+		}
+	}
+	return a.ID < b.ID
+}
+
+func compareBy(a, b *variant.Variant, f SortField) int {
+	switch f.Key {
+	case SortPosition:
+		if c := ChromosomeOrder(a.ReferenceName) - ChromosomeOrder(b.ReferenceName); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.ReferenceName, b.ReferenceName); c != 0 {
+			return c
+		}
+		return int(a.Start) - int(b.Start)
+	case SortAlleleFrequency:
+		x, okx := floatKey(a.AlleleFrequency, f.Desc)
+		y, oky := floatKey(b.AlleleFrequency, f.Desc)
+		return compareOptional(okx, oky, compareFloat(x, y))
+	case SortGene:
+		x, okx := stringKey(a.GeneSymbol, f.Desc)
+		y, oky := stringKey(b.GeneSymbol, f.Desc)
+		return compareOptional(okx, oky, strings.Compare(x, y))
+	case SortSampleCount:
+		return a.SampleCount - b.SampleCount
+	case SortCoverage:
+		okx, oky := a.Coverage != nil, b.Coverage != nil
+		if !okx || !oky {
+			return compareOptional(okx, oky, 0)
+		}
+		return compareFloat(a.Coverage.Median, b.Coverage.Median)
+	}
+	return 0
+}
+
+// compareOptional orders missing values first.
+func compareOptional(okx, oky bool, c int) int {
+	switch {
+	case okx && oky:
+		return c
+	case okx:
+		return 1
+	case oky:
+		return -1
+	}
+	return 0
+}
+
+func compareFloat[T float32 | float64](x, y T) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// floatKey returns the smallest value, or the largest one if descending.
+func floatKey(xs []float32, desc bool) (float32, bool) {
+	if len(xs) == 0 {
+		return 0, false
+	}
+	k := xs[0]
+	for _, x := range xs[1:] {
+		if desc && x > k || !desc && x < k {
+			k = x
+		}
+	}
+	return k, true
+}
+
+// stringKey returns the smallest value, or the largest one if descending.
+func stringKey(xs []string, desc bool) (string, bool) {
+	if len(xs) == 0 {
+		return "", false
+	}
+	k := xs[0]
+	for _, x := range xs[1:] {
+		if desc && x > k || !desc && x < k {
+			k = x
+		}
+	}
+	return k, true
+}
@@ -0,0 +1,79 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/labbcb/brave/variant"
+)
+
+func TestSortFields(t *testing.T) {
+	columns := []Column{{Data: "referenceName"}, {Data: "alleleFrequency"}, {Data: "snpIds"}, {Name: "coverage", Data: "coverage.min"}}
+	ts := []struct {
+		input *Input
+		want  string
+	}{
+		{&Input{}, "[]"},
+		{&Input{Sort: "-af, position,af"}, "[{af true} {position false}]"},
+		{&Input{Sort: "+gene,-sampleCount"}, "[{gene false} {sampleCount true}]"},
+		{&Input{Order: []Order{{1, "desc"}, {0, "asc"}}, Columns: columns}, "[{af true} {position false}]"},
+		{&Input{Order: []Order{{3, "asc"}}, Columns: columns}, "[{coverage false}]"},
+		{&Input{Sort: "gene", Order: []Order{{1, "desc"}}, Columns: columns}, "[{gene false}]"},
+	}
+	for _, tc := range ts {
+		fields, err := tc.input.SortFields()
+		if err != nil {
+			t.Errorf("%+v: %v", tc.input, err)
+			continue
+		}
+		if got := fmt.Sprint(fields); got != tc.want {
+			t.Errorf("%+v: want %s, got %s", tc.input, tc.want, got)
+		}
+	}
+
+	for _, input := range []*Input{
+		{Sort: "qual"},
+		{Order: []Order{{2, "asc"}}, Columns: columns},
+		{Order: []Order{{4, "asc"}}, Columns: columns},
+		{Order: []Order{{0, "up"}}, Columns: columns},
+	} {
+		if _, err := input.SortFields(); !errors.Is(err, ErrInvalidSort) {
+			t.Errorf("%+v: want ErrInvalidSort, got %v", input, err)
+		}
+	}
+}
+
+func TestLess(t *testing.T) {
+	variants := []*variant.Variant{
+		{ID: "a", ReferenceName: "X", Start: 10, AlleleFrequency: []float32{0.1, 0.9}},
+		{ID: "b", ReferenceName: "10", Start: 5, AlleleFrequency: []float32{0.5}},
+		{ID: "c", ReferenceName: "2", Start: 300},
+		{ID: "d", ReferenceName: "2", Start: 20, AlleleFrequency: []float32{0.3}},
+		{ID: "e", ReferenceName: "MT", Start: 1, AlleleFrequency: []float32{0.5}},
+		{ID: "f", ReferenceName: "GL000192.1", Start: 1, AlleleFrequency: []float32{0.2}},
+	}
+
+	ts := []struct {
+		fields []SortField
+		want   string
+	}{
+		{[]SortField{{SortPosition, false}}, "[d c b a e f]"},
+		{[]SortField{{SortPosition, true}}, "[f e a b c d]"},
+		{[]SortField{{SortAlleleFrequency, false}}, "[c a f d b e]"},
+		{[]SortField{{SortAlleleFrequency, true}}, "[a b e d f c]"},
+		{[]SortField{{SortAlleleFrequency, true}, {SortPosition, true}}, "[a e b d f c]"},
+	}
+	for _, tc := range ts {
+		vs := append([]*variant.Variant(nil), variants...)
+		sort.Slice(vs, func(a, b int) bool { return Less(vs[a], vs[b], tc.fields) })
+		var ids []string
+		for _, v := range vs {
+			ids = append(ids, v.ID)
+		}
+		if got := fmt.Sprint(ids); got != tc.want {
+			t.Errorf("%v: want %s, got %s", tc.fields, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/labbcb/brave/beacon"
+)
+
+// handleBeaconInfo describes this Beacon.
+func (s *Server) handleBeaconInfo() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, &beacon.InfoResponse{Meta: beacon.NewMeta(s.Beacon, nil), Response: s.Beacon})
+	}
+}
+
+// handleBeaconConfiguration describes entry types served by this Beacon.
+func (s *Server) handleBeaconConfiguration() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, beacon.NewConfigurationResponse(s.Beacon))
+	}
+}
+
+// handleBeaconFilteringTerms lists filters accepted by genomic variant queries.
+func (s *Server) handleBeaconFilteringTerms() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, beacon.NewFilteringTermsResponse(s.Beacon))
+	}
+}
+
+// handleBeaconVariants queries genomic variants.
+// Request parameters are read from query string (GET) or from Beacon request body (POST).
+func (s *Server) handleBeaconVariants() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req *beacon.Request
+		var err error
+		if r.Method == http.MethodPost {
+			var b []byte
+			if b, err = io.ReadAll(r.Body); err == nil {
+				req, err = beacon.ParseBody(b)
+			}
+		} else {
+			req, err = beacon.ParseQuery(r.URL.Query())
+		}
+		if err != nil {
+			s.beaconError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		response, err := s.Search(req.Input())
+		if err != nil {
+			log.Println(err)
+			s.beaconError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		// results are alleles, and variants that were not split may have more than one
+		total := response.RecordsFiltered
+		if req.Granularity != beacon.Boolean && req.AlternateBases == "" && total > 0 {
+			if total, err = s.DB.CountAlleles(req.Input()); err != nil {
+				log.Println(err)
+				s.beaconError(w, http.StatusInternalServerError, err)
+				return
+			}
+		}
+
+		writeJSON(w, http.StatusOK, beacon.NewResponse(s.Beacon, req, total, response.Variants))
+	}
+}
+
+// beaconError responds with a Beacon error object.
+func (s *Server) beaconError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, &beacon.ErrorResponse{
+		Meta:  beacon.NewMeta(s.Beacon, nil),
+		Error: beacon.Error{ErrorCode: status, ErrorMessage: err.Error()},
+	})
+}
+
+// handleBeaconQuery answers legacy Beacon v1 allele queries.
+// Dataset responses include registered datasets as well as datasets where the allele was found.
+func (s *Server) handleBeaconQuery() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, err := beacon.ParseAlleleRequest(r.URL.Query())
+		if err != nil {
+			s.alleleError(w, http.StatusBadRequest, nil, err)
+			return
+		}
+
+		response, err := s.Search(req.Input())
+		if err != nil {
+			log.Println(err)
+			s.alleleError(w, http.StatusInternalServerError, req, err)
+			return
+		}
+
+		datasets, err := s.DB.ListDatasets()
+		if err != nil {
+			log.Println(err)
+			s.alleleError(w, http.StatusInternalServerError, req, err)
+			return
+		}
+		var ids []string
+		for _, d := range datasets {
+			ids = append(ids, d.ID)
+		}
+
+		writeJSON(w, http.StatusOK, beacon.NewAlleleResponse(s.Beacon, req, ids, response.Variants))
+	}
+}
+
+// alleleError responds with a Beacon v1 allele response whose error is set.
+func (s *Server) alleleError(w http.ResponseWriter, status int, req *beacon.AlleleRequest, err error) {
+	writeJSON(w, status, &beacon.AlleleResponse{
+		BeaconID:      s.Beacon.ID,
+		APIVersion:    beacon.V1APIVersion,
+		AlleleRequest: req,
+		Error:         &beacon.Error{ErrorCode: status, ErrorMessage: err.Error()},
+	})
+}
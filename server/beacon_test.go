@@ -0,0 +1,210 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labbcb/brave/beacon"
+	"github.com/labbcb/brave/dataset"
+	"github.com/labbcb/brave/mem"
+	"github.com/labbcb/brave/search"
+	"github.com/labbcb/brave/variant"
+)
+
+func TestBeaconVariants(t *testing.T) {
+	s := New(mem.New(), "admin", "secret")
+	for _, v := range []*variant.Variant{
+		{DatasetID: "bipmed", AssemblyID: "GRCh37", ReferenceName: "20", Start: 14370, ReferenceBases: "G", AlternateBases: []string{"A"}, AlleleFrequency: []float32{0.5},
+			Type: []string{"missense_variant&splice_region_variant"}},
+		{DatasetID: "bipmed", AssemblyID: "GRCh37", ReferenceName: "20", Start: 1110696, ReferenceBases: "A", AlternateBases: []string{"G", "T"}, AlleleFrequency: []float32{0.333, 0.667}},
+	} {
+		if _, err := s.InsertVariant(v, variant.Insert); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		method, target, body string
+		status               int
+		exists               bool
+		total                int64
+		records              int
+	}{
+		{http.MethodGet, "/api/g_variants?referenceName=20&start=14369", "", 200, true, 1, 1},
+		{http.MethodGet, "/api/g_variants?referenceName=20&start=14370", "", 200, false, 0, 0},
+		{http.MethodGet, "/api/g_variants?referenceName=20&start=0&end=2000000&assemblyId=GRCh37", "", 200, true, 3, 3},
+		{http.MethodGet, "/api/g_variants?referenceName=20&start=0&end=2000000&requestedGranularity=count", "", 200, true, 3, 0},
+		{http.MethodGet, "/api/g_variants?referenceName=20&start=14369&requestedGranularity=boolean", "", 200, true, 0, 0},
+		{http.MethodPost, "/api/g_variants", `{"query":{"requestParameters":{"referenceName":"20","start":[1110695]}}}`, 200, true, 2, 2},
+		{http.MethodGet, "/api/g_variants?referenceName=20&start=1110695&referenceBases=A&alternateBases=T", "", 200, true, 1, 1},
+		{http.MethodGet, "/api/g_variants?referenceName=20&start=1110695&referenceBases=A&alternateBases=C", "", 200, false, 0, 0},
+		{http.MethodGet, "/api/g_variants?referenceName=20", "", 400, false, 0, 0},
+		{http.MethodGet, "/api/g_variants?referenceName=20&start=x", "", 400, false, 0, 0},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.target, strings.NewReader(test.body))
+		w := httptest.NewRecorder()
+		s.Router.ServeHTTP(w, req)
+		if w.Code != test.status {
+			t.Errorf("%s %s: want status %d, got %d: %s", test.method, test.target, test.status, w.Code, w.Body.String())
+			continue
+		}
+		if w.Code != http.StatusOK {
+			continue
+		}
+
+		var resp beacon.Response
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		var total int64
+		if resp.ResponseSummary.NumTotalResults != nil {
+			total = *resp.ResponseSummary.NumTotalResults
+		}
+		var records int
+		if resp.Response != nil {
+			for _, set := range resp.Response.ResultSets {
+				records += len(set.Results)
+			}
+		}
+		if resp.ResponseSummary.Exists != test.exists || total != test.total || records != test.records {
+			t.Errorf("%s %s: want exists %v, total %d, records %d, got %v, %d, %d",
+				test.method, test.target, test.exists, test.total, test.records, resp.ResponseSummary.Exists, total, records)
+		}
+	}
+
+	// variant types are Sequence Ontology terms
+	req := httptest.NewRequest(http.MethodGet, "/api/g_variants?referenceName=20&start=14369", nil)
+	w := httptest.NewRecorder()
+	s.Router.ServeHTTP(w, req)
+	var resp beacon.Response
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	gv := resp.Response.ResultSets[0].Results[0]
+	if got := fmt.Sprint(gv.MolecularAttributes.MolecularEffects); got != "[{SO:0001583 missense_variant} {SO:0001630 splice_region_variant}]" {
+		t.Errorf("unexpected molecular effects %s", got)
+	}
+}
+
+func TestBeaconQuery(t *testing.T) {
+	s := New(mem.New(), "admin", "secret")
+	if err := s.CreateDataset(&dataset.Dataset{ID: "bipmed"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateDataset(&dataset.Dataset{ID: "abraom"}); err != nil {
+		t.Fatal(err)
+	}
+	v := &variant.Variant{DatasetID: "bipmed", AssemblyID: "GRCh37", ReferenceName: "20", Start: 1110696, ReferenceBases: "A",
+		AlternateBases: []string{"G", "T"}, AlleleFrequency: []float32{0.333, 0.667}, TotalSamples: 3, SampleCount: 2}
+	if _, err := s.InsertVariant(v, variant.Insert); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query    string
+		status   int
+		exists   bool
+		datasets string
+	}{
+		{"referenceName=20&start=1110695&referenceBases=A&alternateBases=T&assemblyId=GRCh37", 200, true, "[]"},
+		{"referenceName=20&start=1110695&referenceBases=A&alternateBases=T&assemblyId=GRCh37&includeDatasetResponses=ALL", 200, true, "[abraom:false:0 bipmed:true:0.667]"},
+		{"referenceName=20&start=1110695&referenceBases=A&alternateBases=T&assemblyId=GRCh37&includeDatasetResponses=HIT", 200, true, "[bipmed:true:0.667]"},
+		{"referenceName=20&start=1110695&referenceBases=A&alternateBases=T&assemblyId=GRCh37&includeDatasetResponses=MISS", 200, true, "[abraom:false:0]"},
+		{"referenceName=20&start=1110695&referenceBases=A&alternateBases=C&assemblyId=GRCh37&includeDatasetResponses=ALL", 200, false, "[abraom:false:0 bipmed:false:0]"},
+		{"referenceName=20&start=1110695&referenceBases=A&alternateBases=G&assemblyId=GRCh37&datasetIds=abraom&includeDatasetResponses=ALL", 200, false, "[abraom:false:0]"},
+		{"referenceName=20&start=1110695&referenceBases=A&alternateBases=G&assemblyId=GRCh37&datasetIds=bipmed,abraom&includeDatasetResponses=HIT", 200, true, "[bipmed:true:0.333]"},
+		{"referenceName=20&start=1110695&referenceBases=A&assemblyId=GRCh37", 400, false, "[]"},
+		{"referenceName=20&start=1110695&referenceBases=A&alternateBases=G&assemblyId=GRCh37&includeDatasetResponses=SOME", 400, false, "[]"},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		s.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/query?"+test.query, nil))
+		if w.Code != test.status {
+			t.Errorf("%s: want status %d, got %d: %s", test.query, test.status, w.Code, w.Body.String())
+			continue
+		}
+
+		var resp beacon.AlleleResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if w.Code != http.StatusOK {
+			if resp.Error == nil {
+				t.Errorf("%s: want error, got none", test.query)
+			}
+			continue
+		}
+		var datasets []string
+		for _, d := range resp.DatasetAlleleResponses {
+			datasets = append(datasets, fmt.Sprintf("%s:%v:%v", d.DatasetID, d.Exists, d.Frequency))
+		}
+		if *resp.Exists != test.exists || fmt.Sprint(datasets) != test.datasets {
+			t.Errorf("%s: want exists %v, datasets %s, got %v, %v", test.query, test.exists, test.datasets, *resp.Exists, datasets)
+		}
+	}
+}
+
+func TestBeaconQueryCounts(t *testing.T) {
+	s := New(mem.New(), "admin", "secret")
+	vs := []*variant.Variant{
+		{DatasetID: "bipmed", AssemblyID: "GRCh37", ReferenceName: "20", Start: 1110696, ReferenceBases: "A", AlternateBases: []string{"G", "T"},
+			AlleleCount: []int{1, 3}, AlleleNumber: 10, HetCount: []int{1, 1}, HomAltCount: []int{0, 1}, SampleCount: 5, TotalSamples: 6},
+		{DatasetID: "bipmed", AssemblyID: "GRCh37", ReferenceName: "20", Start: 1110696, ReferenceBases: "A", AlternateBases: []string{"T"},
+			AlleleCount: []int{1}, AlleleNumber: 30, HetCount: []int{1}, HomAltCount: []int{0}, SampleCount: 15, TotalSamples: 20},
+	}
+	if _, err := s.InsertVariants(vs, variant.Insert); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	s.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet,
+		"/query?referenceName=20&start=1110695&referenceBases=A&alternateBases=T&assemblyId=GRCh37&includeDatasetResponses=HIT", nil))
+	var resp beacon.AlleleResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.DatasetAlleleResponses) != 1 {
+		t.Fatalf("want one dataset response, got %s", w.Body.String())
+	}
+	d := resp.DatasetAlleleResponses[0]
+	if d.VariantCount != 2 || d.CallCount != 4 || d.SampleCount != 3 || d.Frequency != 0.1 {
+		t.Errorf("want 2 variants, 4 calls, 3 samples and frequency 0.1, got %+v", d)
+	}
+}
+
+// failingStore fails searches and dataset listing.
+type failingStore struct {
+	*mem.DB
+}
+
+func (failingStore) Search(*search.Input) (*search.Response, error) {
+	return nil, errors.New("database unavailable")
+}
+
+func (failingStore) ListDatasets() ([]*dataset.Dataset, error) {
+	return nil, errors.New("database unavailable")
+}
+
+func TestBeaconQueryError(t *testing.T) {
+	s := New(failingStore{mem.New()}, "admin", "secret")
+	w := httptest.NewRecorder()
+	s.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/query?referenceName=20&start=1110695&referenceBases=A&alternateBases=T&assemblyId=GRCh37", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("want status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+	var resp beacon.AlleleResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("want JSON allele response, got %v", err)
+	}
+	if resp.Error == nil || resp.Error.ErrorCode != http.StatusInternalServerError || resp.AlleleRequest == nil {
+		t.Errorf("want error and allele request in response, got %+v", resp)
+	}
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/labbcb/brave/dataset"
+)
+
+func (s *Server) handleListDatasets() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ds, err := s.DB.ListDatasets()
+		if err != nil {
+			log.Println(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, ds)
+	}
+}
+
+func (s *Server) handleCreateDataset() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var d dataset.Dataset
+		if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if d.ID == "" {
+			http.Error(w, "dataset id is required", http.StatusBadRequest)
+			return
+		}
+
+		if err := s.CreateDataset(&d); err != nil {
+			datasetError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, &d)
+	}
+}
+
+func (s *Server) handleGetDataset() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		d, err := s.DB.GetDataset(mux.Vars(r)["id"])
+		if err != nil {
+			datasetError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, d)
+	}
+}
+
+// handleUpdateDataset replaces dataset metadata, except creation date. Dataset ID is taken from URL path.
+func (s *Server) handleUpdateDataset() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var d dataset.Dataset
+		if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		d.ID = mux.Vars(r)["id"]
+
+		if err := s.UpdateDataset(&d); err != nil {
+			datasetError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, &d)
+	}
+}
+
+// handleRemoveDataset unregisters a dataset. Its variants are removed by DELETE /variants.
+func (s *Server) handleRemoveDataset() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := s.DB.RemoveDataset(mux.Vars(r)["id"]); err != nil {
+			datasetError(w, err)
+			return
+		}
+	}
+}
+
+// handleStats summarizes variants per dataset and assembly.
+// Optional query parameters dataset and assembly restrict the summary.
+func (s *Server) handleStats() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats, err := s.DB.Stats(r.FormValue("dataset"), r.FormValue("assembly"))
+		if err != nil {
+			log.Println(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, stats)
+	}
+}
+
+// datasetError maps registry errors to HTTP status codes.
+func datasetError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, dataset.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, dataset.ErrExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Println(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeJSON encodes v as response body with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("encoding response to json:", err)
+	}
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labbcb/brave/dataset"
+	"github.com/labbcb/brave/mem"
+)
+
+func TestDatasets(t *testing.T) {
+	s := New(mem.New(), "admin", "secret")
+
+	ts := []struct {
+		method, path, body string
+		admin              bool
+		want               int
+	}{
+		{http.MethodPost, "/datasets", `{"id":"bipmed","sampleCount":10}`, false, http.StatusUnauthorized},
+		{http.MethodPost, "/datasets", `{"id":"bipmed","sampleCount":10}`, true, http.StatusCreated},
+		{http.MethodPost, "/datasets", `{"id":"bipmed"}`, true, http.StatusConflict},
+		{http.MethodPost, "/datasets", `{"description":"no id"}`, true, http.StatusBadRequest},
+		{http.MethodGet, "/datasets/bipmed", "", false, http.StatusOK},
+		{http.MethodPut, "/datasets/bipmed", `{"sampleCount":20}`, true, http.StatusOK},
+		{http.MethodPut, "/datasets/other", `{"sampleCount":20}`, true, http.StatusNotFound},
+		{http.MethodGet, "/datasets", "", false, http.StatusOK},
+		{http.MethodDelete, "/datasets/bipmed", "", true, http.StatusOK},
+		{http.MethodGet, "/datasets/bipmed", "", false, http.StatusNotFound},
+	}
+	for _, tc := range ts {
+		req := httptest.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+		if tc.admin {
+			req.SetBasicAuth("admin", "secret")
+		}
+		w := httptest.NewRecorder()
+		s.Router.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("%s %s: want status %d, got %d: %s", tc.method, tc.path, tc.want, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestUpdateDatasetKeepsCreatedAt(t *testing.T) {
+	s := New(mem.New(), "admin", "secret")
+	created := &dataset.Dataset{ID: "bipmed", SampleCount: 10}
+	if err := s.CreateDataset(created); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/datasets/bipmed", bytes.NewBufferString(`{"sampleCount":20,"createdAt":"2000-01-01T00:00:00Z"}`))
+	req.SetBasicAuth("admin", "secret")
+	w := httptest.NewRecorder()
+	s.Router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("want status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/datasets/bipmed", nil)
+	w = httptest.NewRecorder()
+	s.Router.ServeHTTP(w, req)
+	var got dataset.Dataset
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.SampleCount != 20 || !got.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("want sample count 20 and createdAt %s, got %d and %s", created.CreatedAt, got.SampleCount, got.CreatedAt)
+	}
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/labbcb/brave/export"
+	"github.com/labbcb/brave/search"
+	"github.com/labbcb/brave/variant"
+	"github.com/labbcb/brave/vcf"
+)
+
+// Trailers sent after exported variants.
+const (
+	exportCountTrailer = "X-Export-Count"
+	exportErrorTrailer = "X-Export-Error"
+)
+
+// exportInput builds search input from query parameters dataset, assembly, region and q.
+func exportInput(r *http.Request) (*search.Input, error) {
+	q := &search.Query{DatasetID: r.FormValue("dataset"), AssemblyID: r.FormValue("assembly")}
+	if region := r.FormValue("region"); region != "" {
+		p := search.Parse(region)
+		if p.ReferenceName == "" {
+			return nil, fmt.Errorf("invalid region %q", region)
+		}
+		q.ReferenceName, q.Start, q.End = p.ReferenceName, p.Start, p.End
+		q.ReferenceBases, q.AlternateBases = p.ReferenceBases, p.AlternateBases
+	}
+
+	input := &search.Input{Q: r.FormValue("q")}
+	if q.DatasetID != "" || q.AssemblyID != "" || q.ReferenceName != "" {
+		input.Queries = []*search.Query{q}
+	}
+	if _, err := input.Expr(); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+// handleExport streams variants in position order, filtered by dataset, assembly, region and query expression.
+// Query parameter format is ndjson (default), tsv or vcf, gzip=true compresses the response with BGZF (bgzip).
+// Number of variants is sent in X-Total-Count header before streaming, errors while streaming are reported
+// in X-Export-Error trailer, and number of exported variants in X-Export-Count trailer.
+func (s *Server) handleExport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		input, err := exportInput(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		format := r.FormValue("format")
+		compress := r.FormValue("gzip") == "true"
+
+		count, err := s.Search(&search.Input{Queries: input.Queries, Q: input.Q, Length: 1})
+		if err != nil {
+			log.Println(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var header *vcf.Header
+		if format == export.VCF {
+			if header, err = s.vcfHeader(r.FormValue("dataset"), r.FormValue("assembly")); err != nil {
+				log.Println(err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		var out io.Writer = w
+		var zw *vcf.BGZFWriter
+		if compress {
+			zw = vcf.NewBGZFWriter(w)
+			out = zw
+		}
+		ew, err := export.NewWriter(out, format, header)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		filename := "variants." + format
+		if format == "" {
+			filename += export.NDJSON
+		}
+		w.Header().Set("Content-Type", export.ContentType(format))
+		if compress {
+			w.Header().Set("Content-Type", "application/gzip")
+			filename += ".gz"
+		}
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		w.Header().Set("X-Total-Count", strconv.FormatInt(count.RecordsFiltered, 10))
+		w.Header().Set("Trailer", exportCountTrailer+", "+exportErrorTrailer)
+		w.WriteHeader(http.StatusOK)
+
+		var n int64
+		err = s.DB.Export(input, func(v *variant.Variant) error {
+			n++
+			return ew.Write(v)
+		})
+		err = errors.Join(err, ew.Flush())
+		if zw != nil {
+			err = errors.Join(err, zw.Close())
+		}
+		if err != nil {
+			log.Println("exporting variants:", err)
+			w.Header().Set(exportErrorTrailer, err.Error())
+		}
+		w.Header().Set(exportCountTrailer, strconv.FormatInt(n, 10))
+	}
+}
+
+// vcfHeader returns VCF header with contigs of the dataset and assembly, from variant statistics.
+func (s *Server) vcfHeader(datasetID, assemblyID string) (*vcf.Header, error) {
+	stats, err := s.DB.Stats(datasetID, assemblyID)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	h := &vcf.Header{DatasetID: datasetID, AssemblyID: assemblyID}
+	for _, st := range stats {
+		for name := range st.Chromosomes {
+			if !seen[name] {
+				seen[name] = true
+				h.Contigs = append(h.Contigs, name)
+			}
+		}
+	}
+	vcf.SortContigs(h.Contigs)
+	return h, nil
+}
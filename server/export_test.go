@@ -0,0 +1,97 @@
+package server
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labbcb/brave/mem"
+	"github.com/labbcb/brave/variant"
+)
+
+func TestExport(t *testing.T) {
+	s := New(mem.New(), "admin", "secret")
+	for _, v := range []*variant.Variant{
+		{DatasetID: "bipmed", AssemblyID: "hg19", ReferenceName: "X", Start: 100, ReferenceBases: "G", AlternateBases: []string{"A"}},
+		{DatasetID: "bipmed", AssemblyID: "hg19", ReferenceName: "20", Start: 17330, ReferenceBases: "T", AlternateBases: []string{"A"}, AlleleFrequency: []float32{0.005}},
+		{DatasetID: "bipmed", AssemblyID: "hg19", ReferenceName: "20", Start: 14370, ReferenceBases: "G", AlternateBases: []string{"A"}, AlleleFrequency: []float32{0.5}},
+		{DatasetID: "other", AssemblyID: "hg19", ReferenceName: "1", Start: 100, ReferenceBases: "C", AlternateBases: []string{"T"}},
+	} {
+		if _, err := s.InsertVariant(v, variant.Insert); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query  string
+		status int
+		lines  string
+	}{
+		{"?dataset=bipmed", http.StatusOK, "[20:14370 20:17330 X:100]"},
+		{"?dataset=bipmed&region=20:1-15000", http.StatusOK, "[20:14370]"},
+		{"?q=af<0.01", http.StatusOK, "[20:17330]"},
+		{"?dataset=bipmed&format=tsv", http.StatusOK, "[chrom:pos 20:14370 20:17330 X:100]"},
+		{"?dataset=none&format=tsv", http.StatusOK, "[chrom:pos]"},
+		{"?dataset=bipmed&gzip=true", http.StatusOK, "[20:14370 20:17330 X:100]"},
+		{"?dataset=bipmed&format=vcf", http.StatusOK, "[##contig=<ID=20> ##contig=<ID=X> 20:14370 20:17330 X:100]"},
+		{"?dataset=bipmed&format=vcf&gzip=true&region=X:1-1000", http.StatusOK, "[##contig=<ID=20> ##contig=<ID=X> X:100]"},
+		{"?format=vcard", http.StatusBadRequest, ""},
+		{"?region=chr", http.StatusBadRequest, ""},
+		{"?q=af<", http.StatusBadRequest, ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		s.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/export"+test.query, nil))
+		if w.Code != test.status {
+			t.Errorf("%s: want status %d, got %d: %s", test.query, test.status, w.Code, w.Body.String())
+			continue
+		}
+		if w.Code != http.StatusOK {
+			continue
+		}
+
+		var body io.Reader = w.Body
+		if strings.Contains(test.query, "gzip=true") {
+			zr, err := gzip.NewReader(w.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body = zr
+		}
+		var lines []string
+		sc := bufio.NewScanner(body)
+		for sc.Scan() {
+			if strings.Contains(test.query, "format=vcf") {
+				if line := sc.Text(); strings.HasPrefix(line, "##contig") {
+					lines = append(lines, line)
+				} else if !strings.HasPrefix(line, "#") {
+					fields := strings.Split(line, "\t")
+					lines = append(lines, fields[0]+":"+fields[1])
+				}
+				continue
+			}
+			if strings.Contains(test.query, "format=tsv") {
+				fields := strings.Split(sc.Text(), "\t")
+				lines = append(lines, fields[2]+":"+fields[3])
+				continue
+			}
+			var v variant.Variant
+			if err := json.Unmarshal(sc.Bytes(), &v); err != nil {
+				t.Fatal(err)
+			}
+			lines = append(lines, fmt.Sprintf("%s:%d", v.ReferenceName, v.Start))
+		}
+		if got := fmt.Sprint(lines); got != test.lines {
+			t.Errorf("%s: want %s, got %s", test.query, test.lines, got)
+		}
+		if total, count := w.Header().Get("X-Total-Count"), w.Result().Trailer.Get(exportCountTrailer); total != count {
+			t.Errorf("%s: want %s variants, got %s", test.query, total, count)
+		}
+	}
+}
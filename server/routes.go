@@ -1,21 +1,47 @@
 package server
 
 import (
+	"bufio"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/labbcb/brave/search"
 	"github.com/labbcb/brave/variant"
+	"io"
 	"log"
 	"net/http"
 )
 
 func (s *Server) register() {
 	s.Router.HandleFunc("/variants", s.adminOnly(s.handleInsertVariant())).Methods(http.MethodPost)
+	s.Router.HandleFunc("/variants:batch", s.adminOnly(s.handleInsertVariants())).Methods(http.MethodPost)
 	s.Router.HandleFunc("/variants", s.adminOnly(s.handleRemoveVariants())).Methods(http.MethodDelete)
 	s.Router.HandleFunc("/search", s.handleSearch()).Methods(http.MethodPost)
+	s.Router.HandleFunc("/datasets", s.handleListDatasets()).Methods(http.MethodGet)
+	s.Router.HandleFunc("/datasets", s.adminOnly(s.handleCreateDataset())).Methods(http.MethodPost)
+	s.Router.HandleFunc("/datasets/{id}", s.handleGetDataset()).Methods(http.MethodGet)
+	s.Router.HandleFunc("/datasets/{id}", s.adminOnly(s.handleUpdateDataset())).Methods(http.MethodPut)
+	s.Router.HandleFunc("/datasets/{id}", s.adminOnly(s.handleRemoveDataset())).Methods(http.MethodDelete)
+	s.Router.HandleFunc("/stats", s.handleStats()).Methods(http.MethodGet)
+	s.Router.HandleFunc("/export", s.handleExport()).Methods(http.MethodGet)
+	s.Router.HandleFunc("/query", s.handleBeaconQuery()).Methods(http.MethodGet)
+	s.Router.HandleFunc("/api", s.handleBeaconInfo()).Methods(http.MethodGet)
+	s.Router.HandleFunc("/api/info", s.handleBeaconInfo()).Methods(http.MethodGet)
+	s.Router.HandleFunc("/api/configuration", s.handleBeaconConfiguration()).Methods(http.MethodGet)
+	s.Router.HandleFunc("/api/filtering_terms", s.handleBeaconFilteringTerms()).Methods(http.MethodGet)
+	s.Router.HandleFunc("/api/g_variants", s.handleBeaconVariants()).Methods(http.MethodGet, http.MethodPost)
 }
 
+// handleInsertVariant saves a variant. Query parameter onConflict (insert, replace or skip) sets write mode.
+// It responds 201 if variant was created, 409 if it already exists in insert mode, otherwise 200.
 func (s *Server) handleInsertVariant() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		mode, err := variant.ParseWriteMode(r.FormValue("onConflict"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		var v variant.Variant
 		if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
 			log.Println(err)
@@ -23,20 +49,118 @@ func (s *Server) handleInsertVariant() http.HandlerFunc {
 			return
 		}
 
-		if err := s.InsertVariant(&v); err != nil {
+		result, err := s.InsertVariant(&v, mode)
+		if errors.Is(err, variant.ErrDuplicate) {
+			writeJSON(w, http.StatusConflict, conflict{v.ID, result, err.Error()})
+			return
+		}
+		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
+		res := struct {
+			ID string `json:"id"`
+			*variant.WriteResult
+		}{v.ID, result}
+
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
-		if err := json.NewEncoder(w).Encode(map[string]string{"id": v.ID}); err != nil {
+		if result.Created > 0 {
+			w.WriteHeader(http.StatusCreated)
+		}
+		if err := json.NewEncoder(w).Encode(res); err != nil {
 			log.Println("encoding response to json:", err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
 	}
 }
 
+// handleInsertVariants accepts variants as a JSON array or as newline-delimited JSON (NDJSON).
+// Query parameter onConflict (insert, replace or skip) sets write mode.
+// In insert mode existing variants respond 409 with counts of the other variants, which are saved.
+func (s *Server) handleInsertVariants() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		mode, err := variant.ParseWriteMode(r.FormValue("onConflict"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		vs, err := decodeVariants(r.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		result := new(variant.WriteResult)
+		if len(vs) > 0 {
+			result, err = s.InsertVariants(vs, mode)
+			if errors.Is(err, variant.ErrDuplicate) {
+				writeJSON(w, http.StatusConflict, conflict{WriteResult: result, Error: err.Error()})
+				return
+			}
+			if err != nil {
+				log.Println(err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			log.Println("encoding response to json:", err)
+		}
+	}
+}
+
+// conflict is the response to inserts of variants that already exist.
+type conflict struct {
+	ID string `json:"id,omitempty"`
+	*variant.WriteResult
+	Error string `json:"error"`
+}
+
+// decodeVariants reads a JSON array of variants or a stream of JSON variants.
+func decodeVariants(r io.Reader) ([]*variant.Variant, error) {
+	br := bufio.NewReader(r)
+	dec := json.NewDecoder(br)
+
+	var vs []*variant.Variant
+	if b, err := peekNonSpace(br); err == nil && b == '[' {
+		if err := dec.Decode(&vs); err != nil {
+			return nil, err
+		}
+		return vs, nil
+	}
+
+	for {
+		var v variant.Variant
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			return vs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("variant %d: %w", len(vs)+1, err)
+		}
+		vs = append(vs, &v)
+	}
+}
+
+// peekNonSpace returns the first non-whitespace byte without consuming it.
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+			return b, br.UnreadByte()
+		}
+	}
+}
+
 func (s *Server) handleSearch() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var input search.Input
@@ -47,6 +171,11 @@ func (s *Server) handleSearch() http.HandlerFunc {
 		}
 
 		response, err := s.Search(&input)
+		var syntaxErr *search.SyntaxError
+		if errors.As(err, &syntaxErr) || errors.Is(err, search.ErrInvalidSort) || errors.Is(err, search.ErrInvalidCursor) || errors.Is(err, search.ErrInvalidPopulation) || errors.Is(err, search.ErrUnrestrictedPopulation) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			log.Println(err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
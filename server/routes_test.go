@@ -0,0 +1,164 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labbcb/brave/mem"
+	"github.com/labbcb/brave/search"
+	"github.com/labbcb/brave/variant"
+)
+
+func TestInsertVariant(t *testing.T) {
+	db := mem.New()
+	s := New(db, "admin", "secret")
+
+	v := &variant.Variant{DatasetID: "bipmed", AssemblyID: "hg19", ReferenceName: "20", Start: 14370, ReferenceBases: "G", AlternateBases: []string{"A"}}
+	var b bytes.Buffer
+	if err := json.NewEncoder(&b).Encode(v); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/variants", bytes.NewReader(b.Bytes()))
+	w := httptest.NewRecorder()
+	s.Router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("want status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/variants", bytes.NewReader(b.Bytes()))
+	req.SetBasicAuth("admin", "secret")
+	w = httptest.NewRecorder()
+	s.Router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("want status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	want := "bipmed-hg19-20-14370-G-A"
+	if !db.Has(want) {
+		t.Errorf("want variant with id %s, got %v", want, db.Variants())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/variants", bytes.NewReader(b.Bytes()))
+	req.SetBasicAuth("admin", "secret")
+	w = httptest.NewRecorder()
+	s.Router.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("want status %d for existing variant, got %d: %s", http.StatusConflict, w.Code, w.Body.String())
+	}
+}
+
+func TestSearch(t *testing.T) {
+	db := mem.New()
+	s := New(db, "admin", "secret")
+	for _, v := range []*variant.Variant{
+		{DatasetID: "bipmed", AssemblyID: "hg19", ReferenceName: "20", Start: 14370, ReferenceBases: "G", AlternateBases: []string{"A"}},
+		{DatasetID: "bipmed", AssemblyID: "hg19", ReferenceName: "20", Start: 17330, ReferenceBases: "T", AlternateBases: []string{"A"}},
+	} {
+		if _, err := s.InsertVariant(v, variant.Insert); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var b bytes.Buffer
+	input := &search.Input{Draw: 3, Queries: []*search.Query{search.Parse("20:17000-18000")}}
+	if err := json.NewEncoder(&b).Encode(input); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	s.Router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/search", &b))
+	if w.Code != http.StatusOK {
+		t.Fatalf("want status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var resp search.Response
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Draw != 3 || resp.RecordsTotal != 2 || len(resp.Variants) != 1 || resp.Variants[0].Start != 17330 {
+		t.Errorf("unexpected response %+v", resp)
+	}
+}
+
+func TestSearchExpression(t *testing.T) {
+	s := New(mem.New(), "admin", "secret")
+	for _, v := range []*variant.Variant{
+		{DatasetID: "bipmed", AssemblyID: "hg19", ReferenceName: "20", Start: 14370, ReferenceBases: "G", AlternateBases: []string{"A"}, AlleleFrequency: []float32{0.5},
+			Populations: variant.Populations{"AFR": {AlleleFrequency: []float32{0.001}}}},
+		{DatasetID: "bipmed", AssemblyID: "hg19", ReferenceName: "20", Start: 17330, ReferenceBases: "T", AlternateBases: []string{"A"}, AlleleFrequency: []float32{0.005}},
+	} {
+		if _, err := s.InsertVariant(v, variant.Insert); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		body   string
+		status int
+		starts string
+	}{
+		{`{"q":"af<0.01"}`, http.StatusOK, "[17330]"},
+		{`{"q":"region:20:1-20000 AND NOT af<0.01"}`, http.StatusOK, "[14370]"},
+		{`{"q":"af>0.01","queries":[{"referenceName":"20","start":17330}]}`, http.StatusOK, "[]"},
+		{`{"q":"af<0.01 AND"}`, http.StatusBadRequest, ""},
+		{`{"queries":[{"datasetId":"bipmed","population":"AFR","maxAlleleFrequency":0.01}]}`, http.StatusOK, "[14370]"},
+		{`{"q":"dataset:bipmed AND af.AFR>0.01"}`, http.StatusOK, "[]"},
+		{`{"q":"af.AFR<0.01","queries":[{"referenceName":"20","start":14370}]}`, http.StatusOK, "[14370]"},
+		// population frequencies are not indexed, they must be combined with an indexed condition
+		{`{"queries":[{"population":"AFR","maxAlleleFrequency":0.01}]}`, http.StatusBadRequest, ""},
+		{`{"q":"af.AFR>0.01"}`, http.StatusBadRequest, ""},
+		{`{"q":"dataset:bipmed OR af.AFR>0.01"}`, http.StatusBadRequest, ""},
+		{`{"queries":[{"population":"$where","maxAlleleFrequency":0.01}]}`, http.StatusBadRequest, ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		s.Router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/search", bytes.NewBufferString(test.body)))
+		if w.Code != test.status {
+			t.Errorf("%s: want status %d, got %d: %s", test.body, test.status, w.Code, w.Body.String())
+			continue
+		}
+		if w.Code != http.StatusOK {
+			continue
+		}
+		var resp search.Response
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		var starts []int32
+		for _, v := range resp.Variants {
+			starts = append(starts, v.Start)
+		}
+		if got := fmt.Sprint(starts); got != test.starts {
+			t.Errorf("%s: want %s, got %s", test.body, test.starts, got)
+		}
+	}
+}
+
+func TestInsertVariants(t *testing.T) {
+	bodies := []string{
+		`[{"datasetId":"bipmed","assemblyId":"hg19","referenceName":"1","start":100},{"datasetId":"bipmed","assemblyId":"hg19","referenceName":"1","start":200}]`,
+		`{"datasetId":"bipmed","assemblyId":"hg19","referenceName":"1","start":100}
+{"datasetId":"bipmed","assemblyId":"hg19","referenceName":"1","start":200}
+`,
+	}
+	for _, body := range bodies {
+		db := mem.New()
+		s := New(db, "admin", "secret")
+
+		req := httptest.NewRequest(http.MethodPost, "/variants:batch", bytes.NewBufferString(body))
+		req.SetBasicAuth("admin", "secret")
+		w := httptest.NewRecorder()
+		s.Router.ServeHTTP(w, req)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("want status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+		if n := len(db.Variants()); n != 2 {
+			t.Errorf("want 2 variants, got %d", n)
+		}
+	}
+}
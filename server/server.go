@@ -1,29 +1,64 @@
 package server
 
 import (
-	"fmt"
-	"strings"
+	"errors"
+	"time"
+
 	"github.com/gorilla/mux"
-	"github.com/labbcb/brave/mongo"
+	"github.com/labbcb/brave/beacon"
+	"github.com/labbcb/brave/dataset"
 	"github.com/labbcb/brave/search"
 	"github.com/labbcb/brave/variant"
 )
 
+// Store is the storage backend used by the server to persist and query variants.
+type Store interface {
+	// Save stores a variant according to write mode.
+	Save(v *variant.Variant, mode variant.WriteMode) (*variant.WriteResult, error)
+	// SaveMany stores a batch of variants according to write mode.
+	SaveMany(vs []*variant.Variant, mode variant.WriteMode) (*variant.WriteResult, error)
+	// Search returns variants that match the input queries.
+	Search(input *search.Input) (*search.Response, error)
+	// Export calls fn for every variant that matches the input, in position order, without loading all of them in memory.
+	// Paging and sorting of input are ignored.
+	Export(input *search.Input, fn func(v *variant.Variant) error) error
+	// CountAlleles counts alternate alleles of variants that match the input, without returning them.
+	CountAlleles(input *search.Input) (int64, error)
+	// Remove deletes variants given a dataset ID and/or assembly ID.
+	Remove(datasetID, assemblyID string) error
+
+	// SaveDataset registers a dataset, failing with dataset.ErrExists if ID is taken.
+	SaveDataset(d *dataset.Dataset) error
+	// UpdateDataset replaces dataset metadata, failing with dataset.ErrNotFound if it is not registered.
+	UpdateDataset(d *dataset.Dataset) error
+	// GetDataset returns a dataset or dataset.ErrNotFound.
+	GetDataset(id string) (*dataset.Dataset, error)
+	// ListDatasets returns all registered datasets.
+	ListDatasets() ([]*dataset.Dataset, error)
+	// RemoveDataset unregisters a dataset or fails with dataset.ErrNotFound.
+	RemoveDataset(id string) error
+
+	// Stats summarizes variants per dataset and assembly, optionally restricted to a dataset and/or assembly.
+	Stats(datasetID, assemblyID string) ([]*dataset.Stats, error)
+}
+
 // Server contains required dependencies.
 type Server struct {
-	DB       *mongo.DB
+	DB       Store
 	Router   *mux.Router
 	Username string
 	Password string
+	Beacon   *beacon.Info
 }
 
 // New creates a BraVE server.
-func New(mongoClient *mongo.DB, username, password string) *Server {
+func New(db Store, username, password string) *Server {
 	s := &Server{
 		Router:   mux.NewRouter(),
-		DB:       mongoClient,
+		DB:       db,
 		Username: username,
 		Password: password,
+		Beacon:   &beacon.DefaultInfo,
 	}
 	s.register()
 	return s
@@ -34,11 +69,44 @@ func (s *Server) Search(input *search.Input) (*search.Response, error) {
 }
 
 // InsertVariant generates an ID dataset-assembly-reference-start-ref-alt and saves into database.
-func (s *Server) InsertVariant(v *variant.Variant) error {
-	v.ID = fmt.Sprintf("%s-%s-%s-%d-%s-%s", v.DatasetID, v.AssemblyID, v.ReferenceName, v.Start, v.ReferenceBases, strings.Join(v.AlternateBases[:], "_"))
-	return s.DB.Save(v)
+// End position is derived from reference bases if not provided.
+// Write mode defines what happens if there is a variant with the same ID.
+func (s *Server) InsertVariant(v *variant.Variant, mode variant.WriteMode) (*variant.WriteResult, error) {
+	v.ID = v.GenerateID()
+	v.End = v.Stop()
+	return s.DB.Save(v, mode)
+}
+
+// InsertVariants generates IDs and end positions like InsertVariant and saves all variants into database at once.
+func (s *Server) InsertVariants(vs []*variant.Variant, mode variant.WriteMode) (*variant.WriteResult, error) {
+	for _, v := range vs {
+		v.ID = v.GenerateID()
+		v.End = v.Stop()
+	}
+	return s.DB.SaveMany(vs, mode)
 }
 
 func (s *Server) RemoveVariants(datasetID, assemblyID string) error {
 	return s.DB.Remove(datasetID, assemblyID)
 }
+
+// CreateDataset registers a dataset setting its creation date if not provided.
+func (s *Server) CreateDataset(d *dataset.Dataset) error {
+	if d.ID == "" {
+		return errors.New("dataset id is required")
+	}
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = time.Now().UTC()
+	}
+	return s.DB.SaveDataset(d)
+}
+
+// UpdateDataset replaces metadata of a registered dataset, keeping its creation date.
+func (s *Server) UpdateDataset(d *dataset.Dataset) error {
+	old, err := s.DB.GetDataset(d.ID)
+	if err != nil {
+		return err
+	}
+	d.CreatedAt = old.CreatedAt
+	return s.DB.UpdateDataset(d)
+}
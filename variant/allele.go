@@ -0,0 +1,90 @@
+package variant
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GenerateID returns the variant ID dataset-assembly-reference-start-ref-alt.
+// Multi-allelic variants that were not split have their alternate alleles joined with underscores.
+func (v *Variant) GenerateID() string {
+	return fmt.Sprintf("%s-%s-%s-%d-%s-%s", v.DatasetID, v.AssemblyID, v.ReferenceName, v.Start, v.ReferenceBases, strings.Join(v.AlternateBases, "_"))
+}
+
+// AlleleID returns the ID of the i-th alternate allele, the same ID its variant has after splitting (see Split).
+func (v *Variant) AlleleID(i int) string {
+	return fmt.Sprintf("%s-%s-%s-%d-%s-%s", v.DatasetID, v.AssemblyID, v.ReferenceName, v.Start, v.ReferenceBases, v.AlternateBases[i])
+}
+
+// AlleleIndex returns the index of an alternate allele or -1 if variant does not have it.
+func (v *Variant) AlleleIndex(alt string) int {
+	for i, a := range v.AlternateBases {
+		if a == alt {
+			return i
+		}
+	}
+	return -1
+}
+
+// Split decomposes a multi-allelic variant into one variant per alternate allele.
+// Values with one element per ALT (frequencies, counts, including population counts) are subset to the allele.
+// Annotations are subset only if they have one element per ALT, otherwise every variant keeps all of them.
+// Other fields, like allele number and sample count, are the same for all alleles.
+// Variants with at most one alternate allele are returned as is. IDs are not set.
+func (v *Variant) Split() []*Variant {
+	n := len(v.AlternateBases)
+	if n <= 1 {
+		return []*Variant{v}
+	}
+	vs := make([]*Variant, n)
+	for i := range v.AlternateBases {
+		a := *v
+		a.ID = ""
+		a.AlternateBases = []string{v.AlternateBases[i]}
+		a.AlleleFrequency = subsetFloats(v.AlleleFrequency, i, n)
+		a.AlleleCount = subsetInts(v.AlleleCount, i, n)
+		a.HetCount = subsetInts(v.HetCount, i, n)
+		a.HomAltCount = subsetInts(v.HomAltCount, i, n)
+		a.GeneSymbol = subsetStrings(v.GeneSymbol, i, n)
+		a.HGVS = subsetStrings(v.HGVS, i, n)
+		a.Type = subsetStrings(v.Type, i, n)
+		if v.Populations != nil {
+			a.Populations = make(Populations, len(v.Populations))
+			for name, p := range v.Populations {
+				a.Populations[name] = &Population{
+					AlleleCount:     subsetInts(p.AlleleCount, i, n),
+					AlleleNumber:    p.AlleleNumber,
+					AlleleFrequency: subsetFloats(p.AlleleFrequency, i, n),
+					HomAltCount:     subsetInts(p.HomAltCount, i, n),
+					SampleCount:     p.SampleCount,
+				}
+			}
+		}
+		vs[i] = &a
+	}
+	return vs
+}
+
+// subsetInts returns the i-th value of a list with one value per ALT (n), or nil if it does not have n values.
+func subsetInts(xs []int, i, n int) []int {
+	if len(xs) != n {
+		return nil
+	}
+	return []int{xs[i]}
+}
+
+// subsetFloats returns the i-th value of a list with one value per ALT (n), or nil if it does not have n values.
+func subsetFloats(xs []float32, i, n int) []float32 {
+	if len(xs) != n {
+		return nil
+	}
+	return []float32{xs[i]}
+}
+
+// subsetStrings returns the i-th value of a list with one value per ALT (n), or the whole list otherwise.
+func subsetStrings(xs []string, i, n int) []string {
+	if len(xs) != n {
+		return xs
+	}
+	return []string{xs[i]}
+}
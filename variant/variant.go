@@ -1,27 +1,51 @@
 package variant
 
-import "fmt"
+import (
+	"fmt"
+	"regexp"
+)
 
 // Variant is a genomic variant that was annotated, sample data removed and calculated distribution.
 // Variants types supported by VCF are: Integer (32-bit, signed), Float (32-bit, IEEE-754).
 type Variant struct {
-	ID              string        `json:"id" bson:"_id"`                                    // variant id
-	DatasetID       string        `json:"datasetId" bson:"datasetId"`                       // dataset ID
-	TotalSamples    int32         `json:"totalSamples" bson:"totalSamples"`                 // total samples in dataset
-	AssemblyID      string        `json:"assemblyId" bson:"assemblyId"`                     // reference genome version (b37, hg38)
-	SnpIds          []string      `json:"snpIds,omitempty" bson:"snpIds"`                   // ids (ID)
-	ReferenceName   string        `json:"referenceName" bson:"referenceName"`               // contig name (CHROM)
-	Start           int32         `json:"start"`                                            // 0-based position (POS)
-	ReferenceBases  string        `json:"referenceBases,omitempty" bson:"referenceBases"`   // reference bases (REF)
-	AlternateBases  []string      `json:"alternateBases,omitempty" bson:"alternateBases"`   // list of alternate bases (ALT)
-	GeneSymbol      []string      `json:"geneSymbol,omitempty" bson:"geneSymbol"`           // gene symbol, one per ALT
-	AlleleFrequency []float32     `json:"alleleFrequency,omitempty" bson:"alleleFrequency"` // allele frequency (AF), one per ALT
-	SampleCount     int           `json:"sampleCount" bson:"sampleCount"`                   // total samples that have this variant (NS)
-	Coverage        *Distribution `json:"coverage,omitempty"`                               // distribution of coverage (DP)
-	GenotypeQuality *Distribution `json:"genotypeQuality,omitempty" bson:"genotypeQuality"` //distribution of genotype quality (GQ)
-	CLNSIG          string        `json:"clnsig,omitempty"`                                 // clinical significance
-	HGVS            []string      `json:"hgvs,omitempty"`                                   // HGVS nomenclature
-	Type            []string      `json:"type,omitempty"`                                   // variant type
+	ID              string        `json:"id" bson:"_id"`                                        // variant id
+	DatasetID       string        `json:"datasetId" bson:"datasetId"`                           // dataset ID
+	TotalSamples    int32         `json:"totalSamples" bson:"totalSamples"`                     // total samples in dataset
+	AssemblyID      string        `json:"assemblyId" bson:"assemblyId"`                         // reference genome version (b37, hg38)
+	SnpIds          []string      `json:"snpIds,omitempty" bson:"snpIds"`                       // ids (ID)
+	ReferenceName   string        `json:"referenceName" bson:"referenceName"`                   // contig name (CHROM)
+	Start           int32         `json:"start"`                                                // 0-based position (POS)
+	End             int32         `json:"end,omitempty" bson:"end,omitempty"`                   // last reference position (REF length, END or SVLEN)
+	ReferenceBases  string        `json:"referenceBases,omitempty" bson:"referenceBases"`       // reference bases (REF)
+	AlternateBases  []string      `json:"alternateBases,omitempty" bson:"alternateBases"`       // list of alternate bases (ALT)
+	GeneSymbol      []string      `json:"geneSymbol,omitempty" bson:"geneSymbol"`               // gene symbol, one per ALT
+	AlleleFrequency []float32     `json:"alleleFrequency,omitempty" bson:"alleleFrequency"`     // allele frequency (AF), one per ALT
+	SampleCount     int           `json:"sampleCount" bson:"sampleCount"`                       // total samples that have this variant (NS)
+	AlleleCount     []int         `json:"alleleCount,omitempty" bson:"alleleCount,omitempty"`   // allele count (AC), one per ALT
+	AlleleNumber    int           `json:"alleleNumber,omitempty" bson:"alleleNumber,omitempty"` // number of called alleles (AN)
+	HetCount        []int         `json:"hetCount,omitempty" bson:"hetCount,omitempty"`         // heterozygous samples, one per ALT
+	HomAltCount     []int         `json:"homAltCount,omitempty" bson:"homAltCount,omitempty"`   // homozygous alternate samples, one per ALT
+	Populations     Populations   `json:"populations,omitempty" bson:"populations,omitempty"`   // counts per group of samples (population, sex)
+	Coverage        *Distribution `json:"coverage,omitempty"`                                   // distribution of coverage (DP)
+	GenotypeQuality *Distribution `json:"genotypeQuality,omitempty" bson:"genotypeQuality"`     //distribution of genotype quality (GQ)
+	CLNSIG          string        `json:"clnsig,omitempty"`                                     // clinical significance
+	HGVS            []string      `json:"hgvs,omitempty"`                                       // HGVS nomenclature
+	Type            []string      `json:"type,omitempty"`                                       // variant type
+}
+
+// Populations maps names of groups of samples to their counts.
+type Populations map[string]*Population
+
+// PopulationName matches valid names of groups of samples: letters, digits, underscores and hyphens.
+var PopulationName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Population is allele and genotype counts of a group of samples, like a population or sex.
+type Population struct {
+	AlleleCount     []int     `json:"alleleCount" bson:"alleleCount"`         // allele count, one per ALT
+	AlleleNumber    int       `json:"alleleNumber" bson:"alleleNumber"`       // number of called alleles
+	AlleleFrequency []float32 `json:"alleleFrequency" bson:"alleleFrequency"` // allele frequency, one per ALT
+	HomAltCount     []int     `json:"homAltCount" bson:"homAltCount"`         // homozygous alternate samples, one per ALT
+	SampleCount     int       `json:"sampleCount" bson:"sampleCount"`         // samples with called genotypes
 }
 
 // Distribution represents distribution of a list of values.
@@ -34,6 +58,18 @@ type Distribution struct {
 	Mean   float64 `json:"mean"`   // average
 }
 
+// Stop returns the last reference position spanned by variant.
+// If End is not set it is derived from reference bases.
+func (v *Variant) Stop() int32 {
+	if v.End >= v.Start {
+		return v.End
+	}
+	if len(v.ReferenceBases) > 1 {
+		return v.Start + int32(len(v.ReferenceBases)) - 1
+	}
+	return v.Start
+}
+
 func (v *Variant) String() string {
 	return fmt.Sprintf("%s-%s %s:%d (%d/%d) %v %s > %v (AF=%v DP={%s} GQ={%s} GENES=%v CLNSIG=%v HGVS=%s TYPE=%v)",
 		v.DatasetID,
@@ -0,0 +1,59 @@
+package variant
+
+import (
+	"errors"
+	"fmt"
+)
+
+// WriteMode defines what happens when saving a variant whose ID already exists in database.
+type WriteMode string
+
+const (
+	// Insert fails if variant already exists.
+	Insert WriteMode = "insert"
+	// Replace overwrites existing variant.
+	Replace WriteMode = "replace"
+	// Skip keeps existing variant unchanged.
+	Skip WriteMode = "skip"
+)
+
+// ParseWriteMode validates write mode name. Empty name means Insert.
+func ParseWriteMode(s string) (WriteMode, error) {
+	switch m := WriteMode(s); m {
+	case "":
+		return Insert, nil
+	case Insert, Replace, Skip:
+		return m, nil
+	default:
+		return "", fmt.Errorf("invalid write mode %q, must be insert, replace or skip", s)
+	}
+}
+
+// ErrDuplicate reports variants that were not inserted because their IDs already exist.
+// In insert mode the other variants of a batch are still saved and counted.
+var ErrDuplicate = errors.New("duplicate variant")
+
+// DuplicateError reports n duplicate variants, the first one with id.
+func DuplicateError(n int, id string) error {
+	if n == 1 {
+		return fmt.Errorf("%w: %s", ErrDuplicate, id)
+	}
+	return fmt.Errorf("%w: %d variants, the first one is %s", ErrDuplicate, n, id)
+}
+
+// WriteResult counts saved variants by outcome.
+type WriteResult struct {
+	Created  int64 `json:"created"`  // variants that did not exist
+	Replaced int64 `json:"replaced"` // existing variants that were overwritten
+	Skipped  int64 `json:"skipped"`  // existing variants that were kept
+}
+
+// Add sums counts of other result.
+func (r *WriteResult) Add(other *WriteResult) {
+	if other == nil {
+		return
+	}
+	r.Created += other.Created
+	r.Replaced += other.Replaced
+	r.Skipped += other.Skipped
+}
@@ -0,0 +1,29 @@
+package vcf
+
+import (
+	"github.com/brentp/vcfgo"
+	"github.com/labbcb/brave/variant"
+)
+
+// splitAlleles splits the variant built from a multi-allelic record into one variant per ALT (see variant.Variant.Split).
+// Gene symbols, types and HGVS of each variant come from ANN annotations of its allele (ANN Allele field),
+// since there may be any number of annotations per ALT.
+func splitAlleles(v *vcfgo.Variant, nv *variant.Variant) []*variant.Variant {
+	vs := nv.Split()
+	if len(vs) == 1 {
+		return vs
+	}
+	anns := getAnnotations(v)
+	for _, a := range vs {
+		a.GeneSymbol, a.Type, a.HGVS = nil, nil, nil
+		for _, ann := range anns {
+			if len(ann) <= HGVS || ann[0] != a.AlternateBases[0] {
+				continue
+			}
+			a.GeneSymbol = append(a.GeneSymbol, ann[GeneSymbol])
+			a.Type = append(a.Type, ann[Type])
+			a.HGVS = append(a.HGVS, ann[HGVS])
+		}
+	}
+	return vs
+}
@@ -0,0 +1,65 @@
+package vcf
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/labbcb/brave/variant"
+)
+
+func TestSplitAlleles(t *testing.T) {
+	text := `##fileformat=VCFv4.2
+##INFO=<ID=AF,Number=A,Type=Float,Description="Allele frequency">
+##INFO=<ID=ANN,Number=.,Type=String,Description="Functional annotations">
+##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	S1	S2	S3
+1	100	rs1	A	G,T	.	PASS	AF=0.3,0.6;ANN=G|missense_variant|MODERATE|GENE1|ID1|transcript|T1|protein_coding|1/2|c.1A>G|||||,T|synonymous_variant|LOW|GENE1|ID1|transcript|T1|protein_coding|1/2|c.1A>T|||||,T|upstream_gene_variant|MODIFIER|GENE2|ID2|transcript|T2|protein_coding||c.-10A>T|||||	GT	0/1	1/2	2/2
+1	200	.	C	A	.	PASS	.	GT	0/1	0/0	./.
+`
+	groups := map[string][]string{"S1": {"AFR"}, "S2": {"AFR"}, "S3": {"EUR"}}
+	var got []string
+	var records []uint
+	summary, err := IterateOver(strings.NewReader(text), Options{SplitAlleles: true, Counts: GenotypeCounts, Groups: groups}, func(n uint, v *variant.Variant) error {
+		got = append(got, fmt.Sprintf("%s AC=%v AN=%d AF=%v het=%v homalt=%v NS=%d genes=%v types=%v hgvs=%v AFR=%v EUR=%v",
+			v.AlleleID(0), v.AlleleCount, v.AlleleNumber, v.AlleleFrequency, v.HetCount, v.HomAltCount, v.SampleCount,
+			v.GeneSymbol, v.Type, v.HGVS, v.Populations["AFR"].AlleleCount, v.Populations["EUR"].AlleleCount))
+		records = append(records, n)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"--1-100-A-G AC=[2] AN=6 AF=[0.33333334] het=[2] homalt=[0] NS=3 genes=[GENE1] types=[transcript] hgvs=[c.1A>G] AFR=[2] EUR=[0]",
+		"--1-100-A-T AC=[3] AN=6 AF=[0.5] het=[1] homalt=[1] NS=3 genes=[GENE1 GENE2] types=[transcript transcript] hgvs=[c.1A>T c.-10A>T] AFR=[1] EUR=[2]",
+		"--1-200-C-A AC=[1] AN=4 AF=[0.25] het=[1] homalt=[0] NS=2 genes=[] types=[] hgvs=[] AFR=[1] EUR=[0]",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("want %d variants, got %d: %v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("variant %d: want %s, got %s", i, want[i], got[i])
+		}
+	}
+	if fmt.Sprint(records) != "[1 1 2]" {
+		t.Errorf("want record numbers [1 1 2], got %v", records)
+	}
+	if summary.PassedVariants != 2 || summary.SplitRecords != 1 {
+		t.Errorf("want 2 passed and 1 split records, got %+v", summary)
+	}
+
+	// workers deliver all alleles of a record together
+	got = nil
+	if _, err := IterateOver(strings.NewReader(text), Options{SplitAlleles: true, Workers: 2, Ordered: true}, func(n uint, v *variant.Variant) error {
+		got = append(got, v.AlleleID(0))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != "[--1-100-A-G --1-100-A-T --1-200-C-A]" {
+		t.Errorf("unexpected variants with workers %v", got)
+	}
+}
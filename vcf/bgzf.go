@@ -0,0 +1,85 @@
+package vcf
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+)
+
+// bgzfBlockSize is the maximum number of uncompressed bytes per BGZF block, as used by bgzip.
+const bgzfBlockSize = 0xff00
+
+// bgzfEOF is the empty block that marks the end of a BGZF file.
+var bgzfEOF = []byte{
+	0x1f, 0x8b, 0x08, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0x06, 0x00, 0x42, 0x43, 0x02, 0x00,
+	0x1b, 0x00, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+}
+
+// BGZFWriter compresses data in the blocked gzip format (BGZF) of bgzip, so that files can be indexed with tabix.
+// BGZF files are valid gzip files.
+type BGZFWriter struct {
+	w     io.Writer
+	buf   []byte
+	block bytes.Buffer
+	fw    *flate.Writer
+}
+
+// NewBGZFWriter returns a writer that compresses data written to it into w.
+// Close must be called to write remaining data and the end-of-file marker.
+func NewBGZFWriter(w io.Writer) *BGZFWriter {
+	fw, _ := flate.NewWriter(nil, flate.DefaultCompression)
+	return &BGZFWriter{w: w, fw: fw}
+}
+
+// Write buffers p and writes full blocks.
+func (z *BGZFWriter) Write(p []byte) (int, error) {
+	z.buf = append(z.buf, p...)
+	for len(z.buf) >= bgzfBlockSize {
+		if err := z.writeBlock(z.buf[:bgzfBlockSize]); err != nil {
+			return 0, err
+		}
+		z.buf = z.buf[bgzfBlockSize:]
+	}
+	return len(p), nil
+}
+
+// Flush writes buffered data as a block.
+func (z *BGZFWriter) Flush() error {
+	if len(z.buf) == 0 {
+		return nil
+	}
+	err := z.writeBlock(z.buf)
+	z.buf = z.buf[:0]
+	return err
+}
+
+// Close writes buffered data and the end-of-file marker. It does not close the underlying writer.
+func (z *BGZFWriter) Close() error {
+	if err := z.Flush(); err != nil {
+		return err
+	}
+	_, err := z.w.Write(bgzfEOF)
+	return err
+}
+
+// writeBlock writes data as a gzip member with the BC extra subfield holding block size minus one.
+func (z *BGZFWriter) writeBlock(data []byte) error {
+	z.block.Reset()
+	z.block.Write([]byte{0x1f, 0x8b, 0x08, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0x06, 0x00, 0x42, 0x43, 0x02, 0x00, 0x00, 0x00})
+	z.fw.Reset(&z.block)
+	if _, err := z.fw.Write(data); err != nil {
+		return err
+	}
+	if err := z.fw.Close(); err != nil {
+		return err
+	}
+	z.block.Write(binary.LittleEndian.AppendUint32(nil, crc32.ChecksumIEEE(data)))
+	z.block.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(data))))
+
+	b := z.block.Bytes()
+	binary.LittleEndian.PutUint16(b[16:], uint16(len(b)-1))
+	_, err := z.w.Write(b)
+	return err
+}
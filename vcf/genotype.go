@@ -0,0 +1,234 @@
+package vcf
+
+import (
+	"fmt"
+
+	"github.com/brentp/vcfgo"
+	"github.com/labbcb/brave/variant"
+)
+
+const (
+	// AC is the allele count in genotypes for each ALT allele
+	AC = "AC"
+	// AN is the total number of alleles in called genotypes
+	AN = "AN"
+)
+
+// Sources of allele counts, allele frequency and number of samples with data.
+const (
+	// InfoCounts uses INFO fields (AC, AN, AF and NS), computing from genotypes those that are missing.
+	InfoCounts = "info"
+	// GenotypeCounts computes values from sample genotypes, using INFO fields only for sites without genotypes.
+	GenotypeCounts = "genotypes"
+)
+
+// ParseCounts checks a source of allele counts. Empty source means InfoCounts.
+func ParseCounts(s string) (string, error) {
+	switch s {
+	case "":
+		return InfoCounts, nil
+	case InfoCounts, GenotypeCounts:
+		return s, nil
+	}
+	return "", fmt.Errorf("invalid allele counts source %q, must be %s or %s", s, InfoCounts, GenotypeCounts)
+}
+
+// Counts are allele and genotype counts of a VCF record computed from sample genotypes (GT).
+type Counts struct {
+	AlleleCount  []int // number of called ALT alleles, one per ALT
+	AlleleNumber int   // number of called alleles
+	Het          []int // samples with the ALT and a different allele, one per ALT
+	HomAlt       []int // samples whose alleles are all the ALT, one per ALT, including haploid calls
+	Samples      int   // samples with at least one called allele
+}
+
+// CountGenotypes counts called alleles and genotypes of samples. Samples must be parsed.
+// It returns nil if no sample has a genotype.
+// Partially missing genotypes (./1) count in allele counts but not as heterozygous or homozygous.
+func CountGenotypes(v *vcfgo.Variant) *Counts {
+	return countSamples(v.Samples, len(v.Alternate))
+}
+
+// countSamples counts genotypes of samples of a record with n ALT alleles, or returns nil if no sample has a genotype.
+func countSamples(samples []*vcfgo.SampleGenotype, n int) *Counts {
+	var c *Counts
+	for _, s := range samples {
+		if s == nil || len(s.GT) == 0 {
+			continue
+		}
+		if c == nil {
+			c = &Counts{
+				AlleleCount: make([]int, n),
+				Het:         make([]int, n),
+				HomAlt:      make([]int, n),
+			}
+		}
+
+		called := 0
+		for _, a := range s.GT {
+			if a < 0 {
+				continue
+			}
+			called++
+			if a > 0 && a <= n {
+				c.AlleleCount[a-1]++
+			}
+		}
+		if called == 0 {
+			continue
+		}
+		c.AlleleNumber += called
+		c.Samples++
+		if called < len(s.GT) {
+			continue
+		}
+
+		first, hom := s.GT[0], true
+		for _, a := range s.GT[1:] {
+			hom = hom && a == first
+		}
+		for i := 0; i < n; i++ {
+			if hom && first == i+1 {
+				c.HomAlt[i]++
+			} else if !hom && contains(s.GT, i+1) {
+				c.Het[i]++
+			}
+		}
+	}
+	return c
+}
+
+// AlleleFrequency returns allele count divided by allele number, one per ALT, or nil if there is no called allele.
+func (c *Counts) AlleleFrequency() []float32 {
+	if c.AlleleNumber == 0 {
+		return nil
+	}
+	afs := make([]float32, len(c.AlleleCount))
+	for i, n := range c.AlleleCount {
+		afs[i] = float32(n) / float32(c.AlleleNumber)
+	}
+	return afs
+}
+
+// setCounts sets allele counts, allele frequency and sample count of variant from INFO fields and genotype counts
+// according to source (InfoCounts or GenotypeCounts).
+func setCounts(nv *variant.Variant, v *vcfgo.Variant, source string) {
+	info := struct {
+		ac []int
+		an int
+		af []float32
+		ns int
+	}{getIntSlice(v, AC), GetAttributeAsInt(v, AN, -1), GetAttributeAsFloatSlice(v, AF, nil), GetAttributeAsInt(v, NS, -1)}
+
+	c := CountGenotypes(v)
+	if c == nil {
+		nv.AlleleCount, nv.AlleleFrequency = info.ac, info.af
+		nv.AlleleNumber, nv.SampleCount = max(info.an, 0), max(info.ns, 0)
+		return
+	}
+	nv.HetCount, nv.HomAltCount = c.Het, c.HomAlt
+	nv.AlleleCount, nv.AlleleNumber, nv.AlleleFrequency, nv.SampleCount = c.AlleleCount, c.AlleleNumber, c.AlleleFrequency(), c.Samples
+	if source == GenotypeCounts {
+		return
+	}
+	if info.ac != nil {
+		nv.AlleleCount = info.ac
+	}
+	if info.an >= 0 {
+		nv.AlleleNumber = info.an
+	}
+	if info.af != nil {
+		nv.AlleleFrequency = info.af
+	}
+	if info.ns >= 0 {
+		nv.SampleCount = info.ns
+	}
+}
+
+// getIntSlice gets an INFO field as integers, or nil if missing.
+func getIntSlice(v *vcfgo.Variant, key string) []int {
+	i, err := v.Info_.Get(key)
+	if err != nil {
+		return nil
+	}
+	switch x := i.(type) {
+	case []int:
+		return x
+	case int:
+		return []int{x}
+	}
+	return nil
+}
+
+// maskGenotypes sets genotypes of samples with read depth (DP) below opts.MinDP or genotype quality (GQ)
+// below opts.MinGQ as missing. Missing DP or GQ values don't mask genotypes.
+// Samples must be parsed. It returns the number of called genotypes masked.
+func maskGenotypes(v *vcfgo.Variant, opts Options) int {
+	if opts.MinDP <= 0 && opts.MinGQ <= 0 {
+		return 0
+	}
+	var masked int
+	for _, s := range v.Samples {
+		if !called(s) || len(s.GT) == 0 {
+			continue
+		}
+		if !(opts.MinDP > 0 && hasField(s, DP) && s.DP < opts.MinDP || opts.MinGQ > 0 && hasField(s, GQ) && s.GQ < opts.MinGQ) {
+			continue
+		}
+		for i := range s.GT {
+			s.GT[i] = -1
+		}
+		masked++
+	}
+	return masked
+}
+
+// hasField reports whether sample has a non-missing FORMAT field.
+func hasField(s *vcfgo.SampleGenotype, key string) bool {
+	value, ok := s.Fields[key]
+	return ok && value != "" && value != "."
+}
+
+// callRate returns the fraction of samples with at least one called allele.
+// It returns false if no sample has a genotype.
+func callRate(v *vcfgo.Variant) (float64, bool) {
+	var total, n int
+	for _, s := range v.Samples {
+		if s == nil || len(s.GT) == 0 {
+			continue
+		}
+		total++
+		if called(s) {
+			n++
+		}
+	}
+	if total == 0 {
+		return 0, false
+	}
+	return float64(n) / float64(total), true
+}
+
+// called reports whether sample has at least one called allele or has no genotype (GT) at all.
+func called(s *vcfgo.SampleGenotype) bool {
+	if s == nil {
+		return false
+	}
+	if len(s.GT) == 0 {
+		return true
+	}
+	for _, a := range s.GT {
+		if a >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(xs []int, x int) bool {
+	for _, y := range xs {
+		if y == x {
+			return true
+		}
+	}
+	return false
+}
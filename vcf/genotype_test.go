@@ -0,0 +1,89 @@
+package vcf
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/labbcb/brave/variant"
+)
+
+func TestCounts(t *testing.T) {
+	text := `##fileformat=VCFv4.2
+##INFO=<ID=NS,Number=1,Type=Integer,Description="Number of samples with data">
+##INFO=<ID=AF,Number=A,Type=Float,Description="Allele frequency">
+##INFO=<ID=AC,Number=A,Type=Integer,Description="Allele count">
+##INFO=<ID=AN,Number=1,Type=Integer,Description="Allele number">
+##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	S1	S2	S3	S4
+1	100	.	A	G,T	.	PASS	NS=4;AF=0.9,0.1;AC=9,1;AN=10	GT	0/1	1|2	2/2	./.
+1	200	.	C	G	.	PASS	.	GT	0/0	./1	1	0/1
+`
+	tests := []struct {
+		counts string
+		want   []string
+	}{
+		{InfoCounts, []string{
+			"AC=[9 1] AN=10 AF=[0.9 0.1] NS=4 het=[2 1] homalt=[0 1]",
+			"AC=[3] AN=6 AF=[0.5] NS=4 het=[1] homalt=[1]",
+		}},
+		{GenotypeCounts, []string{
+			"AC=[2 3] AN=6 AF=[0.33333334 0.5] NS=3 het=[2 1] homalt=[0 1]",
+			"AC=[3] AN=6 AF=[0.5] NS=4 het=[1] homalt=[1]",
+		}},
+	}
+	for _, test := range tests {
+		var got []string
+		if _, err := IterateOver(strings.NewReader(text), Options{Counts: test.counts}, func(n uint, v *variant.Variant) error {
+			got = append(got, fmt.Sprintf("AC=%v AN=%d AF=%v NS=%d het=%v homalt=%v",
+				v.AlleleCount, v.AlleleNumber, v.AlleleFrequency, v.SampleCount, v.HetCount, v.HomAltCount))
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%s: want %q, got %q", test.counts, test.want, got)
+		}
+	}
+}
+
+func TestMaskGenotypes(t *testing.T) {
+	text := `##fileformat=VCFv4.2
+##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">
+##FORMAT=<ID=DP,Number=1,Type=Integer,Description="Read depth">
+##FORMAT=<ID=GQ,Number=1,Type=Integer,Description="Genotype quality">
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	S1	S2	S3	S4
+1	100	.	A	G	.	PASS	.	GT:DP:GQ	0/1:20:99	1/1:5:99	0/0:30:10	./.:0:0
+1	200	.	C	G	.	PASS	.	GT:DP:GQ	0/1:20:99	1/1:25:.	0/0:30:50	0/1:40:60
+`
+	for _, workers := range []int{1, 3} {
+		opts := Options{Counts: GenotypeCounts, MinDP: 10, MinGQ: 20, MinCallRate: 0.5, Workers: workers, Ordered: true}
+		var got []string
+		summary, err := IterateOver(strings.NewReader(text), opts, func(n uint, v *variant.Variant) error {
+			got = append(got, fmt.Sprintf("%d AC=%v AN=%d NS=%d DP=%v", v.Start, v.AlleleCount, v.AlleleNumber, v.SampleCount, v.Coverage.Min))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// first site keeps only S1, call rate 0.25; missing GQ of S2 at second site does not mask it
+		if want := "[200 AC=[4] AN=8 NS=4 DP=20]"; fmt.Sprint(got) != want {
+			t.Errorf("workers %d: want %s, got %s", workers, want, got)
+		}
+		if summary.MaskedGenotypes != 2 || summary.LowCallRate != 1 || summary.PassedVariants != 1 {
+			t.Errorf("workers %d: unexpected summary %+v", workers, summary)
+		}
+	}
+
+	var dps []string
+	if _, err := IterateOver(strings.NewReader(text), Options{}, func(n uint, v *variant.Variant) error {
+		dps = append(dps, v.Coverage.String())
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// missing genotype of S4 is not part of DP distribution
+	if !strings.HasPrefix(dps[0], "min=5.00") {
+		t.Errorf("want DP distribution of called samples, got %s", dps[0])
+	}
+}
@@ -0,0 +1,89 @@
+package vcf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/brentp/vcfgo"
+	"github.com/labbcb/brave/variant"
+)
+
+// ReadGroups reads a sample-to-group mapping. Each line has a sample name followed by the groups it belongs to,
+// like population and sex, separated by tabs or spaces. Empty lines and lines starting with # are ignored.
+func ReadGroups(r io.Reader) (map[string][]string, error) {
+	groups := make(map[string][]string)
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: sample %s has no group", n, fields[0])
+		}
+		for _, g := range fields[1:] {
+			if !variant.PopulationName.MatchString(g) {
+				return nil, fmt.Errorf("line %d: invalid group name %q", n, g)
+			}
+		}
+		groups[fields[0]] = append(groups[fields[0]], fields[1:]...)
+	}
+	return groups, sc.Err()
+}
+
+// groupSamples returns, for each group, indices of its samples among selected samples (or all samples if nil).
+// Samples that are not in VCF header or were not selected are ignored.
+func groupSamples(h *vcfgo.Header, selected []int, groups map[string][]string) map[string][]int {
+	if len(groups) == 0 {
+		return nil
+	}
+	names := h.SampleNames
+	if selected != nil {
+		names = make([]string, len(selected))
+		for i, j := range selected {
+			names[i] = h.SampleNames[j]
+		}
+	}
+
+	indices := make(map[string][]int)
+	for i, name := range names {
+		for _, g := range groups[name] {
+			indices[g] = append(indices[g], i)
+		}
+	}
+	return indices
+}
+
+// countPopulations counts genotypes of each group of samples.
+// Groups whose samples have no genotype are not counted.
+func countPopulations(v *vcfgo.Variant, groups map[string][]int) variant.Populations {
+	if len(groups) == 0 {
+		return nil
+	}
+	pops := make(variant.Populations, len(groups))
+	for g, indices := range groups {
+		samples := make([]*vcfgo.SampleGenotype, 0, len(indices))
+		for _, i := range indices {
+			if i < len(v.Samples) {
+				samples = append(samples, v.Samples[i])
+			}
+		}
+		c := countSamples(samples, len(v.Alternate))
+		if c == nil {
+			continue
+		}
+		pops[g] = &variant.Population{
+			AlleleCount:     c.AlleleCount,
+			AlleleNumber:    c.AlleleNumber,
+			AlleleFrequency: c.AlleleFrequency(),
+			HomAltCount:     c.HomAlt,
+			SampleCount:     c.Samples,
+		}
+	}
+	if len(pops) == 0 {
+		return nil
+	}
+	return pops
+}
@@ -0,0 +1,62 @@
+package vcf
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/labbcb/brave/variant"
+)
+
+func TestPopulations(t *testing.T) {
+	groups, err := ReadGroups(strings.NewReader(`# sample population sex
+S1	AFR	female
+S2	AFR	male
+S3 EUR female
+
+S4	EUR	male
+S9	AMR	female
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := `##fileformat=VCFv4.2
+##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	S1	S2	S3	S4
+1	100	.	A	G,T	.	PASS	.	GT	0/1	1/1	0/2	./.
+`
+	var v *variant.Variant
+	opts := Options{Groups: groups, ExcludeSamples: []string{"S2"}}
+	if _, err := IterateOver(strings.NewReader(text), opts, func(n uint, nv *variant.Variant) error {
+		v = nv
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"AFR":    "AC=[1 0] AN=2 AF=[0.5 0] homalt=[0 0] NS=1",
+		"EUR":    "AC=[0 1] AN=2 AF=[0 0.5] homalt=[0 0] NS=1",
+		"female": "AC=[1 1] AN=4 AF=[0.25 0.25] homalt=[0 0] NS=2",
+		"male":   "AC=[0 0] AN=0 AF=[] homalt=[0 0] NS=0",
+	}
+	if len(v.Populations) != len(want) {
+		t.Errorf("want populations %v, got %v", want, v.Populations)
+	}
+	for name, w := range want {
+		p := v.Populations[name]
+		if p == nil {
+			t.Errorf("%s: missing population", name)
+			continue
+		}
+		got := fmt.Sprintf("AC=%v AN=%d AF=%v homalt=%v NS=%d", p.AlleleCount, p.AlleleNumber, p.AlleleFrequency, p.HomAltCount, p.SampleCount)
+		if got != w {
+			t.Errorf("%s: want %s, got %s", name, w, got)
+		}
+	}
+
+	if _, err := ReadGroups(strings.NewReader("S1 AFR.x\n")); err == nil {
+		t.Error("want error for invalid group name")
+	}
+}
@@ -0,0 +1,149 @@
+package vcf
+
+import (
+	"sync"
+
+	"github.com/brentp/vcfgo"
+	"github.com/labbcb/brave/variant"
+)
+
+// record is a VCF record that passed filters, numbered in delivery order (seq) and file order (n).
+type record struct {
+	seq, n uint
+	v      *vcfgo.Variant
+}
+
+// result is variants built from a record.
+type result struct {
+	seq, n uint
+	vs     []*variant.Variant
+	masked int
+	err    error
+}
+
+// iterateParallel reads records in one goroutine, builds variants in opts.Workers goroutines
+// and delivers them to doSomething in the calling goroutine.
+// At most a fixed window of records is in flight, so a slow consumer blocks the reader (back-pressure).
+func iterateParallel(vcfReader *vcfgo.Reader, opts Options, doSomething func(n uint, v *variant.Variant) error) (VCFSummary, error) {
+	window := make(chan struct{}, 4*opts.Workers)
+	records := make(chan record, opts.Workers)
+	results := make(chan result, opts.Workers)
+	done := make(chan struct{})
+
+	var summary VCFSummary
+	var readErr error
+	var reader sync.WaitGroup
+	reader.Add(1)
+	go func() {
+		defer reader.Done()
+		defer close(records)
+
+		var seq uint
+		for {
+			v := vcfReader.Read()
+			if v == nil {
+				return
+			}
+			summary.TotalVariants += 1
+
+			skip, err := opts.ResumeAfter.skip(summary.TotalVariants, v)
+			if err != nil {
+				readErr = err
+				return
+			}
+			if skip {
+				summary.SkippedVariants += 1
+				continue
+			}
+
+			if opts.Filter && !passed(v) {
+				continue
+			}
+
+			select {
+			case window <- struct{}{}:
+			case <-done:
+				return
+			}
+			records <- record{seq, summary.TotalVariants, v}
+			seq++
+		}
+	}()
+
+	var workers sync.WaitGroup
+	for i := 0; i < opts.Workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for rec := range records {
+				vs, masked, err := buildVariant(rec.v, opts)
+				results <- result{rec.seq, rec.n, vs, masked, err}
+			}
+		}()
+	}
+	go func() {
+		workers.Wait()
+		close(results)
+	}()
+
+	var passedVariants, maskedGenotypes, lowCallRate, splitRecords uint
+	var errs []error
+	var failed error
+	deliver := func(res result) {
+		<-window
+		if failed != nil {
+			return
+		}
+		if res.err != nil {
+			errs = append(errs, res.err)
+		}
+		maskedGenotypes += uint(res.masked)
+		if res.vs == nil {
+			lowCallRate += 1
+			return
+		}
+		if len(res.vs) > 1 {
+			splitRecords += 1
+		}
+		for _, v := range res.vs {
+			if err := doSomething(res.n, v); err != nil {
+				failed = err
+				close(done)
+				return
+			}
+		}
+		passedVariants += 1
+	}
+
+	pending := make(map[uint]result)
+	var next uint
+	for res := range results {
+		if !opts.Ordered {
+			deliver(res)
+			continue
+		}
+		pending[res.seq] = res
+		for {
+			res, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			deliver(res)
+		}
+	}
+
+	reader.Wait()
+	summary.PassedVariants = passedVariants
+	summary.MaskedGenotypes = maskedGenotypes
+	summary.LowCallRate = lowCallRate
+	summary.SplitRecords = splitRecords
+	if failed != nil {
+		return summary, failed
+	}
+	if readErr != nil {
+		return summary, readErr
+	}
+	return summary, readError(vcfReader, errs)
+}
@@ -0,0 +1,59 @@
+package vcf
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/brentp/vcfgo"
+)
+
+// selectSamples returns indices of samples that are in opts.Samples, or all samples if it is empty,
+// and are not in opts.ExcludeSamples. It returns nil if all samples are selected.
+// Sample names that are not in VCF header are reported as error.
+func selectSamples(h *vcfgo.Header, opts Options) ([]int, error) {
+	if len(opts.Samples) == 0 && len(opts.ExcludeSamples) == 0 {
+		return nil, nil
+	}
+
+	index := make(map[string]int, len(h.SampleNames))
+	for i, name := range h.SampleNames {
+		index[name] = i
+	}
+	var unknown []string
+	check := func(names []string) map[string]bool {
+		set := make(map[string]bool, len(names))
+		for _, name := range names {
+			if _, ok := index[name]; !ok {
+				unknown = append(unknown, name)
+			}
+			set[name] = true
+		}
+		return set
+	}
+	include, exclude := check(opts.Samples), check(opts.ExcludeSamples)
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("samples not found in VCF header: %s", strings.Join(unknown, ", "))
+	}
+
+	selected := []int{}
+	for i, name := range h.SampleNames {
+		if (len(include) == 0 || include[name]) && !exclude[name] {
+			selected = append(selected, i)
+		}
+	}
+	return selected, nil
+}
+
+// subsetSamples keeps parsed samples at indices, if not nil.
+func subsetSamples(v *vcfgo.Variant, indices []int) {
+	if indices == nil {
+		return
+	}
+	samples := make([]*vcfgo.SampleGenotype, 0, len(indices))
+	for _, i := range indices {
+		if i < len(v.Samples) {
+			samples = append(samples, v.Samples[i])
+		}
+	}
+	v.Samples = samples
+}
@@ -0,0 +1,72 @@
+package vcf
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/labbcb/brave/variant"
+)
+
+func TestSamples(t *testing.T) {
+	text := `##fileformat=VCFv4.2
+##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">
+##FORMAT=<ID=DP,Number=1,Type=Integer,Description="Read depth">
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO	FORMAT	S1	S2	S3	S4
+1	100	.	A	G	.	PASS	.	GT:DP	0/1:10	1/1:20	0/0:30	0/1:40
+`
+	tests := []struct {
+		include, exclude []string
+		want             string
+	}{
+		{nil, nil, "total=4 AC=[4] AN=8 DP=10-40"},
+		{[]string{"S2", "S3"}, nil, "total=2 AC=[2] AN=4 DP=20-30"},
+		{nil, []string{"S2"}, "total=3 AC=[2] AN=6 DP=10-40"},
+		{[]string{"S1", "S2"}, []string{"S2"}, "total=1 AC=[1] AN=2 DP=10-10"},
+	}
+	for _, test := range tests {
+		opts := Options{Counts: GenotypeCounts, Samples: test.include, ExcludeSamples: test.exclude, Workers: 2}
+		var got string
+		if _, err := IterateOver(strings.NewReader(text), opts, func(n uint, v *variant.Variant) error {
+			got = fmt.Sprintf("total=%d AC=%v AN=%d DP=%v-%v", v.TotalSamples, v.AlleleCount, v.AlleleNumber, v.Coverage.Min, v.Coverage.Max)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("samples %v, excluded %v: want %s, got %s", test.include, test.exclude, test.want, got)
+		}
+	}
+
+	// INFO fields describe all samples, so counts of a subset are computed from genotypes unless INFO counts are chosen
+	info := strings.Replace(text, "##FORMAT", `##INFO=<ID=NS,Number=1,Type=Integer,Description="Number of samples with data">
+##INFO=<ID=AF,Number=A,Type=Float,Description="Allele frequency">
+##INFO=<ID=AC,Number=A,Type=Integer,Description="Allele count">
+##INFO=<ID=AN,Number=1,Type=Integer,Description="Allele number">
+##FORMAT`, 1)
+	info = strings.Replace(info, "PASS\t.\t", "PASS\tNS=10;AF=0.25;AC=5;AN=20\t", 1)
+	for counts, want := range map[string]string{
+		"":         "AF=[0.5] NS=2 AN=4",
+		InfoCounts: "AF=[0.25] NS=10 AN=20",
+	} {
+		var got string
+		opts := Options{Counts: counts, Samples: []string{"S2", "S3"}}
+		if _, err := IterateOver(strings.NewReader(info), opts, func(n uint, v *variant.Variant) error {
+			got = fmt.Sprintf("AF=%v NS=%d AN=%d", v.AlleleFrequency, v.SampleCount, v.AlleleNumber)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("counts %q of subset: want %s, got %s", counts, want, got)
+		}
+	}
+
+	_, err := IterateOver(strings.NewReader(text), Options{Samples: []string{"S1", "S5"}, ExcludeSamples: []string{"S6"}}, func(n uint, v *variant.Variant) error {
+		t.Error("want no variant when samples are unknown")
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "S5, S6") {
+		t.Errorf("want error reporting unknown samples, got %v", err)
+	}
+}
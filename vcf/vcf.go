@@ -1,9 +1,11 @@
 package vcf
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/brentp/vcfgo"
@@ -23,6 +25,12 @@ const (
 	DP = "DP"
 	// GQ is per-sample conditional genotype quality
 	GQ = "GQ"
+	// END is the end position of structural variants
+	END = "END"
+	// SVLEN is the difference in length between REF and ALT alleles of structural variants
+	SVLEN = "SVLEN"
+	// SVTYPE is the type of structural variant
+	SVTYPE = "SVTYPE"
 	// GeneSymbol is an ANN field for gene symbol, one per alternate bases
 	GeneSymbol = 3
 	// Type is an ANN field for variant type, one per alternate bases
@@ -32,42 +40,94 @@ const (
 )
 
 type VCFSummary struct {
-	TotalVariants  uint
-	PassedVariants uint
+	TotalVariants   uint
+	PassedVariants  uint
+	SkippedVariants uint // records skipped when resuming from checkpoint
+	MaskedGenotypes uint // genotypes masked as missing because of low DP or GQ
+	LowCallRate     uint // variants dropped because call rate was below MinCallRate
+	SplitRecords    uint // multi-allelic records split into one variant per ALT
 }
 
-// IterateOver reads a VCF file (from io.Reader) and yeld varint to caller's function.
+// Options controls how VCF records are read and converted to variants.
+type Options struct {
+	Filter     bool   // skip variants that did not pass all filters (FILTER = PASS or .)
+	DatasetID  string // dataset ID assigned to variants
+	AssemblyID string // assembly ID assigned to variants
+	Workers    int    // number of goroutines building variants, 1 or less reads sequentially
+	Ordered    bool   // deliver variants in the same order as VCF records when using workers
+	Counts     string // source of allele counts, frequency and sample count: InfoCounts or GenotypeCounts, see CountsSource
+	MinDP      int    // genotypes with lower read depth (FORMAT/DP) are masked as missing, 0 disables
+	MinGQ      int    // genotypes with lower genotype quality (FORMAT/GQ) are masked as missing, 0 disables
+	// MinCallRate drops variants with a lower fraction of samples with called genotypes, after masking.
+	MinCallRate float64
+	// ResumeAfter skips records up to the checkpoint record, which must match the VCF record.
+	ResumeAfter *Checkpoint
+	// Samples restricts aggregation to these samples, all samples if empty.
+	Samples []string
+	// ExcludeSamples removes these samples from aggregation.
+	ExcludeSamples []string
+	// Groups maps sample names to groups (population, sex) whose allele counts are computed separately.
+	Groups map[string][]string
+	// SplitAlleles delivers multi-allelic records as one variant per ALT, all with the same record number.
+	SplitAlleles bool
+
+	samples []int            // indices of selected samples, nil for all samples
+	groups  map[string][]int // indices of selected samples of each group
+}
+
+// CountsSource returns the source of allele counts of variants: Counts if it is set, otherwise
+// GenotypeCounts if samples are subset or genotypes are masked, since INFO fields describe all samples, or InfoCounts.
+func (o Options) CountsSource() string {
+	if o.Counts != "" {
+		return o.Counts
+	}
+	if len(o.Samples) > 0 || len(o.ExcludeSamples) > 0 || o.MinDP > 0 || o.MinGQ > 0 {
+		return GenotypeCounts
+	}
+	return InfoCounts
+}
+
+// Checkpoint identifies the last VCF record that was successfully imported.
+type Checkpoint struct {
+	Record        uint   `json:"record"`        // 1-based record number, counting records that did not pass filters
+	ReferenceName string `json:"referenceName"` // contig name (CHROM) without chr prefix
+	Start         int32  `json:"start"`         // position (POS)
+}
+
+// skip reports whether record n was already imported according to checkpoint.
+// It fails if the checkpoint record does not match VCF record, meaning that VCF file has changed.
+func (cp *Checkpoint) skip(n uint, v *vcfgo.Variant) (bool, error) {
+	if cp == nil || n > cp.Record {
+		return false, nil
+	}
+	if n == cp.Record && (strings.TrimPrefix(v.Chromosome, "chr") != cp.ReferenceName || int32(v.Pos) != cp.Start) {
+		return false, fmt.Errorf("checkpoint record %d at %s:%d does not match VCF record at %s:%d",
+			cp.Record, cp.ReferenceName, cp.Start, v.Chromosome, v.Pos)
+	}
+	return true, nil
+}
+
+// IterateOver reads a VCF file (from io.Reader) and yeld varint to caller's function along with its 1-based record number.
+// Samples not found in VCF header are reported as error before reading records.
 // It returns VCFSummary with total of variants read and total of variants that passed all filters (FILTER = PASS or .).
 // If err is not nil, VCFSummary will have the total variants so far.
-func IterateOver(r io.Reader, doFilter bool, datasetID, assemblyID string, doSomething func(v *variant.Variant) error) (VCFSummary, error) {
-	vcfReader, err := vcfgo.NewReader(r, false)
+// With more than one worker, records are parsed, converted to variants and delivered in separate stages;
+// doSomething is always called from a single goroutine.
+func IterateOver(r io.Reader, opts Options, doSomething func(n uint, v *variant.Variant) error) (VCFSummary, error) {
+	vcfReader, err := vcfgo.NewReader(r, true)
 	if err != nil {
 		return VCFSummary{}, err
 	}
-	totalSamples := len(vcfReader.Header.SampleNames)
-
-	buildVariant := func(v *vcfgo.Variant) *variant.Variant {
-		return &variant.Variant{
-			DatasetID:       datasetID,
-			TotalSamples:    int32(totalSamples),
-			AssemblyID:      assemblyID,
-			SnpIds:          getSnpIds(v),
-			ReferenceName:   strings.TrimPrefix(v.Chromosome, "chr"),
-			Start:           int32(v.Pos),
-			ReferenceBases:  v.Reference,
-			AlternateBases:  v.Alternate,
-			GeneSymbol:      GetAnnotationColumn(v, GeneSymbol),
-			AlleleFrequency: GetAttributeAsFloatSlice(v, AF, nil),
-			SampleCount:     GetAttributeAsInt(v, NS, 0),
-			Coverage:        CalculateDistribution(GetSamplesDP(v)),
-			GenotypeQuality: CalculateDistribution(GetSamplesGQ(v)),
-			CLNSIG:          GetAttributeAsString(v, CLNSIG, ""),
-			HGVS:            GetAnnotationColumn(v, HGVS),
-			Type:            GetAnnotationColumn(v, Type),
-		}
+	if opts.samples, err = selectSamples(vcfReader.Header, opts); err != nil {
+		return VCFSummary{}, err
+	}
+	opts.groups = groupSamples(vcfReader.Header, opts.samples, opts.Groups)
+	if opts.Workers > 1 {
+		return iterateParallel(vcfReader, opts, doSomething)
 	}
 
-	var totalVariants, passedVariants uint
+	var summary VCFSummary
+	var errs []error
 
 	for {
 		v := vcfReader.Read()
@@ -75,19 +135,148 @@ func IterateOver(r io.Reader, doFilter bool, datasetID, assemblyID string, doSom
 			break
 		}
 
-		totalVariants += 1
+		summary.TotalVariants += 1
 
-		if doFilter && v.Filter != "PASS" && v.Filter != "." {
+		skip, err := opts.ResumeAfter.skip(summary.TotalVariants, v)
+		if err != nil {
+			return summary, err
+		}
+		if skip {
+			summary.SkippedVariants += 1
 			continue
 		}
 
-		if err := doSomething(buildVariant(v)); err != nil {
-			return VCFSummary{totalVariants, passedVariants}, err
+		if opts.Filter && !passed(v) {
+			continue
+		}
+
+		vs, masked, err := buildVariant(v, opts)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		summary.MaskedGenotypes += uint(masked)
+		if vs == nil {
+			summary.LowCallRate += 1
+			continue
+		}
+		if len(vs) > 1 {
+			summary.SplitRecords += 1
+		}
+		for _, nv := range vs {
+			if err := doSomething(summary.TotalVariants, nv); err != nil {
+				return summary, err
+			}
 		}
 
-		passedVariants += 1
+		summary.PassedVariants += 1
 	}
-	return VCFSummary{totalVariants, passedVariants}, vcfReader.Error()
+	return summary, readError(vcfReader, errs)
+}
+
+// passed reports whether variant passed all filters.
+func passed(v *vcfgo.Variant) bool {
+	return v.Filter == "PASS" || v.Filter == "."
+}
+
+// buildVariant parses samples, keeps selected samples, masks low quality genotypes and converts a VCF record to variants,
+// one per ALT if opts.SplitAlleles is set or a single variant otherwise.
+// It returns the number of masked genotypes and no variant if call rate is below opts.MinCallRate.
+// Malformed samples are reported as error but variants are still built.
+func buildVariant(v *vcfgo.Variant, opts Options) ([]*variant.Variant, int, error) {
+	err := v.Header.ParseSamples(v)
+	if err != nil {
+		err = fmt.Errorf("line %d: %w", v.LineNumber, err)
+	}
+	totalSamples := len(v.Header.SampleNames)
+	if opts.samples != nil {
+		subsetSamples(v, opts.samples)
+		totalSamples = len(opts.samples)
+	}
+	masked := maskGenotypes(v, opts)
+	if rate, ok := callRate(v); ok && rate < opts.MinCallRate {
+		return nil, masked, err
+	}
+	nv := &variant.Variant{
+		DatasetID:       opts.DatasetID,
+		TotalSamples:    int32(totalSamples),
+		AssemblyID:      opts.AssemblyID,
+		SnpIds:          getSnpIds(v),
+		ReferenceName:   strings.TrimPrefix(v.Chromosome, "chr"),
+		Start:           int32(v.Pos),
+		End:             getEnd(v),
+		ReferenceBases:  v.Reference,
+		AlternateBases:  v.Alternate,
+		GeneSymbol:      GetAnnotationColumn(v, GeneSymbol),
+		Coverage:        CalculateDistribution(GetSamplesDP(v)),
+		GenotypeQuality: CalculateDistribution(GetSamplesGQ(v)),
+		CLNSIG:          GetAttributeAsString(v, CLNSIG, ""),
+		HGVS:            GetAnnotationColumn(v, HGVS),
+		Type:            GetAnnotationColumn(v, Type),
+	}
+	setCounts(nv, v, opts.CountsSource())
+	nv.Populations = countPopulations(v, opts.groups)
+	if opts.SplitAlleles {
+		return splitAlleles(v, nv), masked, err
+	}
+	return []*variant.Variant{nv}, masked, err
+}
+
+// readError combines errors reported by VCF reader and errors found while building variants.
+func readError(vcfReader *vcfgo.Reader, errs []error) error {
+	if err := vcfReader.Error(); err != nil {
+		errs = append([]error{err}, errs...)
+	}
+	return errors.Join(errs...)
+}
+
+// getEnd returns the last reference position of variant.
+// It is END if present, POS plus SVLEN of deletions and other structural variants (except insertions)
+// or the last position of REF.
+func getEnd(v *vcfgo.Variant) int32 {
+	if end, ok := getInt(v, END); ok && end >= int(v.Pos) {
+		return int32(end)
+	}
+	if svlen, ok := getInt(v, SVLEN); ok && GetAttributeAsString(v, SVTYPE, "") != "INS" {
+		if svlen < 0 {
+			svlen = -svlen
+		}
+		return int32(v.Pos) + int32(svlen)
+	}
+	return int32(v.Pos) + int32(len(v.Reference)) - 1
+}
+
+// getInt gets an INFO field as integer. Multiple values use the first one.
+func getInt(v *vcfgo.Variant, key string) (int, bool) {
+	i, err := v.Info_.Get(key)
+	if err != nil {
+		return 0, false
+	}
+	switch x := i.(type) {
+	case int:
+		return x, true
+	case []int:
+		if len(x) > 0 {
+			return x[0], true
+		}
+	case []interface{}:
+		if len(x) > 0 {
+			return getIntValue(x[0])
+		}
+	default:
+		return getIntValue(x)
+	}
+	return 0, false
+}
+
+func getIntValue(x interface{}) (int, bool) {
+	switch n := x.(type) {
+	case int:
+		return n, true
+	case string:
+		i, err := strconv.Atoi(strings.SplitN(n, ",", 2)[0])
+		return i, err == nil
+	}
+	return 0, false
 }
 
 func getSnpIds(v *vcfgo.Variant) []string {
@@ -112,27 +301,34 @@ func GetAttributeAsString(v *vcfgo.Variant, key string, defaultValue string) str
 
 // GetAnnotationColumn gets an ANN column by its index
 func GetAnnotationColumn(v *vcfgo.Variant, index int) []string {
+	var columns []string
+	for _, a := range getAnnotations(v) {
+		columns = append(columns, a[index])
+	}
+	return columns
+}
+
+// getAnnotations gets ANN annotations split into fields.
+func getAnnotations(v *vcfgo.Variant) [][]string {
 	i, _ := v.Info_.Get(ANN)
 	if i == nil {
 		return nil
 	}
 
+	var values []string
 	switch ann := i.(type) {
 	case string:
-		var columns []string
-		for _, a := range strings.Split(ann, ",") {
-			columns = append(columns, strings.Split(a, "|")[index])
-		}
-		return columns
+		values = strings.Split(ann, ",")
 	case []string:
-		var columns []string
-		for _, a := range ann {
-			columns = append(columns, strings.Split(a, "|")[index])
-		}
-		return columns
+		values = ann
 	default:
 		panic(fmt.Sprintf("invalid type %T", ann))
 	}
+	anns := make([][]string, len(values))
+	for n, a := range values {
+		anns[n] = strings.Split(a, "|")
+	}
+	return anns
 }
 
 // GetAttributeAsFloatSlice gets AF values
@@ -153,19 +349,25 @@ func GetAttributeAsInt(v *vcfgo.Variant, key string, defaultValue int) int {
 	return i.(int)
 }
 
-// GetSamplesDP get per-sample DP
+// GetSamplesDP get per-sample DP, except samples with missing or masked genotypes
 func GetSamplesDP(v *vcfgo.Variant) []int {
 	var dps []int
 	for _, s := range v.Samples {
+		if !called(s) {
+			continue
+		}
 		dps = append(dps, s.DP)
 	}
 	return dps
 }
 
-// GetSamplesGQ per-sample GQ
+// GetSamplesGQ per-sample GQ, except samples with missing or masked genotypes
 func GetSamplesGQ(v *vcfgo.Variant) []int {
 	var gqs []int
 	for _, s := range v.Samples {
+		if !called(s) {
+			continue
+		}
 		gqs = append(gqs, s.GQ)
 	}
 	return gqs
@@ -214,7 +416,7 @@ func CalculateDistribution(xs []int) *variant.Distribution {
 	// calculate q25, q75 and mean
 	return &variant.Distribution{
 		Min:    float64(xs[0]),
-		Q25:    float64(xs[int(0.25*(float64(length)+1))-1]),
+		Q25:    float64(xs[max(int(0.25*(float64(length)+1))-1, 0)]),
 		Median: median,
 		Q75:    float64(xs[int(0.75*(float64(length)+1))-1]),
 		Max:    float64(xs[length-1]),
@@ -0,0 +1,116 @@
+package vcf
+
+import (
+	"compress/gzip"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/labbcb/brave/variant"
+)
+
+func readTestData(t *testing.T, opts Options) ([]*variant.Variant, VCFSummary) {
+	f, err := os.Open("../testdata/test.ann.vcf.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var vs []*variant.Variant
+	summary, _ := IterateOver(r, opts, func(n uint, v *variant.Variant) error {
+		vs = append(vs, v)
+		return nil
+	})
+	return vs, summary
+}
+
+func TestIterateOver(t *testing.T) {
+	want, summary := readTestData(t, Options{Filter: true, DatasetID: "bipmed", AssemblyID: "hg19"})
+	if summary.TotalVariants != 5 || summary.PassedVariants != 4 || len(want) != 4 {
+		t.Fatalf("want 5 total and 4 passed variants, got %+v", summary)
+	}
+
+	got, summary := readTestData(t, Options{Filter: true, DatasetID: "bipmed", AssemblyID: "hg19", Workers: 3, Ordered: true})
+	if summary.TotalVariants != 5 || summary.PassedVariants != 4 {
+		t.Fatalf("want 5 total and 4 passed variants, got %+v", summary)
+	}
+	for i := range want {
+		if got[i].String() != want[i].String() {
+			t.Errorf("variant %d: want %v, got %v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestEnd(t *testing.T) {
+	text := `##fileformat=VCFv4.2
+##INFO=<ID=END,Number=1,Type=Integer,Description="End position">
+##INFO=<ID=SVLEN,Number=.,Type=Integer,Description="Length of structural variant">
+##INFO=<ID=SVTYPE,Number=1,Type=String,Description="Type of structural variant">
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO
+1	100	.	A	G	.	PASS	.
+1	200	.	ACGT	A	.	PASS	.
+1	300	.	N	<DEL>	.	PASS	SVTYPE=DEL;SVLEN=-500
+1	400	.	N	<DUP>	.	PASS	SVTYPE=DUP;END=1400
+1	500	.	N	<INS>	.	PASS	SVTYPE=INS;SVLEN=300
+`
+	var ends []int32
+	if _, err := IterateOver(strings.NewReader(text), Options{}, func(n uint, v *variant.Variant) error {
+		ends = append(ends, v.End)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(ends), "[100 203 800 1400 500]"; got != want {
+		t.Errorf("want ends %s, got %s", want, got)
+	}
+}
+
+func TestCalculateDistribution(t *testing.T) {
+	// two samples remain when others are masked or missing
+	d := CalculateDistribution([]int{8, 4})
+	if d.Min != 4 || d.Q25 != 4 || d.Median != 6 || d.Max != 8 || d.Mean != 6 {
+		t.Errorf("unexpected distribution %s", d)
+	}
+}
+
+func TestResumeAfter(t *testing.T) {
+	text := `##fileformat=VCFv4.2
+#CHROM	POS	ID	REF	ALT	QUAL	FILTER	INFO
+20	100	.	A	G	.	PASS	.
+20	200	.	C	T	.	q10	.
+20	300	.	G	A	.	PASS	.
+20	400	.	T	C	.	PASS	.
+20	500	.	A	T	.	PASS	.
+`
+	for _, workers := range []int{1, 3} {
+		opts := Options{Filter: true, Workers: workers, Ordered: true, ResumeAfter: &Checkpoint{Record: 3, ReferenceName: "20", Start: 300}}
+		var records []uint
+		summary, err := IterateOver(strings.NewReader(text), opts, func(n uint, v *variant.Variant) error {
+			records = append(records, n)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if summary.TotalVariants != 5 || summary.SkippedVariants != 3 || summary.PassedVariants != 2 {
+			t.Errorf("workers %d: want 5 total, 3 skipped and 2 passed variants, got %+v", workers, summary)
+		}
+		if got := fmt.Sprint(records); got != "[4 5]" {
+			t.Errorf("workers %d: want records [4 5], got %s", workers, got)
+		}
+
+		opts.ResumeAfter = &Checkpoint{Record: 3, ReferenceName: "20", Start: 200}
+		_, err = IterateOver(strings.NewReader(text), opts, func(n uint, v *variant.Variant) error {
+			t.Errorf("workers %d: unexpected record %d", workers, n)
+			return nil
+		})
+		if err == nil || !strings.Contains(err.Error(), "does not match VCF record at 20:300") {
+			t.Errorf("workers %d: want checkpoint mismatch error, got %v", workers, err)
+		}
+	}
+}
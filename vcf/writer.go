@@ -0,0 +1,232 @@
+package vcf
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labbcb/brave/search"
+	"github.com/labbcb/brave/variant"
+)
+
+// INFO fields written by Writer, besides AC, AN, AF, NS, CLNSIG and END.
+const (
+	// HET is the number of heterozygous samples for each ALT allele
+	HET = "HET"
+	// HOMALT is the number of homozygous alternate samples for each ALT allele
+	HOMALT = "HOMALT"
+	// GENE is the gene symbol of each annotation
+	GENE = "GENE"
+	// TYPE is the variant type of each annotation
+	TYPE = "TYPE"
+	// HGVSKey is the HGVS nomenclature of each annotation
+	HGVSKey = "HGVS"
+	// DPDIST is the distribution of per-sample read depth
+	DPDIST = "DPDIST"
+	// GQDIST is the distribution of per-sample genotype quality
+	GQDIST = "GQDIST"
+)
+
+// infoHeader lists INFO definitions in the order fields are written.
+var infoHeader = []string{
+	`##INFO=<ID=AC,Number=A,Type=Integer,Description="Allele count in genotypes, for each ALT allele">`,
+	`##INFO=<ID=AN,Number=1,Type=Integer,Description="Total number of alleles in called genotypes">`,
+	`##INFO=<ID=AF,Number=A,Type=Float,Description="Allele frequency, for each ALT allele">`,
+	`##INFO=<ID=HET,Number=A,Type=Integer,Description="Number of heterozygous samples, for each ALT allele">`,
+	`##INFO=<ID=HOMALT,Number=A,Type=Integer,Description="Number of homozygous alternate samples, for each ALT allele">`,
+	`##INFO=<ID=NS,Number=1,Type=Integer,Description="Number of samples with data">`,
+	`##INFO=<ID=END,Number=1,Type=Integer,Description="End position of the variant">`,
+	`##INFO=<ID=GENE,Number=.,Type=String,Description="Gene symbol of each annotation">`,
+	`##INFO=<ID=TYPE,Number=.,Type=String,Description="Variant type of each annotation">`,
+	`##INFO=<ID=HGVS,Number=.,Type=String,Description="HGVS nomenclature of each annotation">`,
+	`##INFO=<ID=CLNSIG,Number=.,Type=String,Description="Clinical significance">`,
+	`##INFO=<ID=DPDIST,Number=6,Type=Float,Description="Distribution of per-sample read depth: min, q25, median, q75, max and mean">`,
+	`##INFO=<ID=GQDIST,Number=6,Type=Float,Description="Distribution of per-sample genotype quality: min, q25, median, q75, max and mean">`,
+}
+
+// ErrUnsorted reports a variant written after a variant that comes after it in position order.
+var ErrUnsorted = errors.New("variants are not sorted by position")
+
+var positionOrder = []search.SortField{{Key: search.SortPosition}}
+
+// Header describes a sites-only VCF file.
+type Header struct {
+	DatasetID  string   // written as ##dataset
+	AssemblyID string   // written as ##reference
+	Contigs    []string // contig names, written as ##contig in this order
+}
+
+// Writer writes variants as sites-only VCF records (without samples) in position order.
+type Writer struct {
+	w      *bufio.Writer
+	header *Header
+	wrote  bool
+	last   *variant.Variant
+}
+
+// NewWriter creates a VCF writer. Header is written before the first record.
+func NewWriter(w io.Writer, h *Header) *Writer {
+	if h == nil {
+		h = &Header{}
+	}
+	return &Writer{w: bufio.NewWriter(w), header: h}
+}
+
+func (w *Writer) writeHeader() {
+	if w.wrote {
+		return
+	}
+	w.wrote = true
+
+	fmt.Fprintln(w.w, "##fileformat=VCFv4.2")
+	fmt.Fprintln(w.w, "##source=BraVE")
+	if w.header.AssemblyID != "" {
+		fmt.Fprintf(w.w, "##reference=%s\n", w.header.AssemblyID)
+	}
+	if w.header.DatasetID != "" {
+		fmt.Fprintf(w.w, "##dataset=%s\n", w.header.DatasetID)
+	}
+	for _, c := range w.header.Contigs {
+		fmt.Fprintf(w.w, "##contig=<ID=%s>\n", c)
+	}
+	for _, line := range infoHeader {
+		fmt.Fprintln(w.w, line)
+	}
+	fmt.Fprintln(w.w, "#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO")
+}
+
+// Write writes a variant as a VCF record. Variants must be written in position order, otherwise it returns ErrUnsorted.
+func (w *Writer) Write(v *variant.Variant) error {
+	if l := w.last; l != nil && (v.ReferenceName != l.ReferenceName || v.Start != l.Start) && search.Less(v, l, positionOrder) {
+		return fmt.Errorf("%w: %s:%d after %s:%d", ErrUnsorted, v.ReferenceName, v.Start, w.last.ReferenceName, w.last.Start)
+	}
+	w.last = v
+	w.writeHeader()
+
+	_, err := fmt.Fprintf(w.w, "%s\t%d\t%s\t%s\t%s\t.\t.\t%s\n",
+		v.ReferenceName,
+		v.Start,
+		join(v.SnpIds, ";"),
+		orMissing(v.ReferenceBases),
+		join(v.AlternateBases, ","),
+		info(v))
+	return err
+}
+
+// Flush writes the header, if no variant was written, and buffered data to the underlying writer.
+func (w *Writer) Flush() error {
+	w.writeHeader()
+	return w.w.Flush()
+}
+
+// info formats INFO column of variant.
+func info(v *variant.Variant) string {
+	var fields []string
+	add := func(key string, values []string) {
+		if len(values) > 0 {
+			fields = append(fields, key+"="+strings.Join(values, ","))
+		}
+	}
+
+	var afs []string
+	for _, af := range v.AlleleFrequency {
+		afs = append(afs, strconv.FormatFloat(float64(af), 'g', -1, 32))
+	}
+	add(AC, ints(v.AlleleCount))
+	if v.AlleleNumber > 0 {
+		fields = append(fields, AN+"="+strconv.Itoa(v.AlleleNumber))
+	}
+	add(AF, afs)
+	add(HET, ints(v.HetCount))
+	add(HOMALT, ints(v.HomAltCount))
+	fields = append(fields, NS+"="+strconv.Itoa(v.SampleCount))
+	if stop := (&variant.Variant{Start: v.Start, ReferenceBases: v.ReferenceBases}).Stop(); v.Stop() != stop {
+		fields = append(fields, END+"="+strconv.Itoa(int(v.Stop())))
+	}
+	add(GENE, escapeAll(v.GeneSymbol))
+	add(TYPE, escapeAll(v.Type))
+	add(HGVSKey, escapeAll(v.HGVS))
+	if v.CLNSIG != "" {
+		add(CLNSIG, escapeAll(strings.Split(v.CLNSIG, ",")))
+	}
+	add(DPDIST, distribution(v.Coverage))
+	add(GQDIST, distribution(v.GenotypeQuality))
+	return strings.Join(fields, ";")
+}
+
+func ints(xs []int) []string {
+	var values []string
+	for _, x := range xs {
+		values = append(values, strconv.Itoa(x))
+	}
+	return values
+}
+
+func distribution(d *variant.Distribution) []string {
+	if d == nil {
+		return nil
+	}
+	var values []string
+	for _, x := range []float64{d.Min, d.Q25, d.Median, d.Q75, d.Max, d.Mean} {
+		values = append(values, strconv.FormatFloat(x, 'g', -1, 64))
+	}
+	return values
+}
+
+// infoEscaper percent-encodes characters that are not allowed in INFO values (VCF 4.3).
+var infoEscaper = strings.NewReplacer("%", "%25", ";", "%3B", "=", "%3D", ",", "%2C", " ", "%20", "\t", "%09", "\n", "%0A", "\r", "%0D")
+
+// escapeAll escapes values, using . for empty values.
+func escapeAll(values []string) []string {
+	escaped := make([]string, len(values))
+	for i, s := range values {
+		escaped[i] = orMissing(infoEscaper.Replace(s))
+	}
+	return escaped
+}
+
+func join(values []string, sep string) string {
+	return orMissing(strings.Join(values, sep))
+}
+
+func orMissing(s string) string {
+	if s == "" {
+		return "."
+	}
+	return s
+}
+
+// SortVariants sorts variants in position order: natural chromosome order then start position.
+func SortVariants(vs []*variant.Variant) {
+	sort.SliceStable(vs, func(i, j int) bool {
+		return search.Less(vs[i], vs[j], positionOrder)
+	})
+}
+
+// SortContigs sorts contig names in natural chromosome order, the order of Writer records.
+func SortContigs(names []string) {
+	sort.Slice(names, func(i, j int) bool {
+		if a, b := search.ChromosomeOrder(names[i]), search.ChromosomeOrder(names[j]); a != b {
+			return a < b
+		}
+		return names[i] < names[j]
+	})
+}
+
+// Contigs returns distinct reference names of variants in natural chromosome order.
+func Contigs(vs []*variant.Variant) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, v := range vs {
+		if !seen[v.ReferenceName] {
+			seen[v.ReferenceName] = true
+			names = append(names, v.ReferenceName)
+		}
+	}
+	SortContigs(names)
+	return names
+}
@@ -0,0 +1,100 @@
+package vcf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/labbcb/brave/variant"
+)
+
+func TestWriter(t *testing.T) {
+	vs := []*variant.Variant{
+		{ReferenceName: "X", Start: 100, ReferenceBases: "A", AlternateBases: []string{"G"}, SampleCount: 3},
+		{ReferenceName: "2", Start: 500, End: 1500, ReferenceBases: "N", AlternateBases: []string{"<DEL>"}},
+		{ReferenceName: "2", Start: 100, SnpIds: []string{"rs1", "rs2"}, ReferenceBases: "G", AlternateBases: []string{"A", "T"},
+			AlleleFrequency: []float32{0.25, 0.5}, SampleCount: 10, GeneSymbol: []string{"SCN1A", ""},
+			HGVS: []string{"c.1A>G", "c.1A>T"}, CLNSIG: "Pathogenic,Likely pathogenic",
+			Coverage: &variant.Distribution{Min: 1, Q25: 2, Median: 3, Q75: 4, Max: 5, Mean: 3.5}},
+	}
+	SortVariants(vs)
+
+	var b bytes.Buffer
+	w := NewWriter(&b, &Header{DatasetID: "bipmed", AssemblyID: "hg19", Contigs: Contigs(vs)})
+	for _, v := range vs {
+		if err := w.Write(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"##reference=hg19\n##dataset=bipmed\n##contig=<ID=2>\n##contig=<ID=X>\n",
+		"2\t100\trs1;rs2\tG\tA,T\t.\t.\tAF=0.25,0.5;NS=10;GENE=SCN1A,.;HGVS=c.1A>G,c.1A>T;CLNSIG=Pathogenic,Likely%20pathogenic;DPDIST=1,2,3,4,5,3.5\n",
+		"2\t500\t.\tN\t<DEL>\t.\t.\tNS=0;END=1500\n",
+		"X\t100\t.\tA\tG\t.\t.\tNS=3\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("want %q in\n%s", want, out)
+		}
+	}
+
+	// written VCF is read back
+	var got []*variant.Variant
+	if _, err := IterateOver(strings.NewReader(out), Options{}, func(n uint, v *variant.Variant) error {
+		got = append(got, v)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0].SampleCount != 10 || got[0].AlleleFrequency[1] != 0.5 || got[1].End != 1500 {
+		t.Errorf("unexpected variants %v", got)
+	}
+
+	if err := w.Write(vs[0]); !errors.Is(err, ErrUnsorted) {
+		t.Errorf("want %v, got %v", ErrUnsorted, err)
+	}
+}
+
+func TestBGZFWriter(t *testing.T) {
+	data := bytes.Repeat([]byte("1\t100\t.\tA\tG\t.\t.\tNS=3\n"), 10000)
+	var b bytes.Buffer
+	z := NewBGZFWriter(&b)
+	if _, err := z.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasSuffix(b.Bytes(), bgzfEOF) {
+		t.Error("want BGZF end-of-file marker")
+	}
+	// blocks are chained by their BSIZE field
+	blocks := 0
+	for rest := b.Bytes(); len(rest) > 0; blocks++ {
+		if len(rest) < 18 || rest[12] != 'B' || rest[13] != 'C' {
+			t.Fatalf("invalid block %d", blocks)
+		}
+		rest = rest[int(rest[16])|int(rest[17])<<8+1:]
+	}
+	if want := len(data)/bgzfBlockSize + 2; blocks != want {
+		t.Errorf("want %d blocks, got %d", want, blocks)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("want %d bytes, got %d", len(data), len(got))
+	}
+}